@@ -28,6 +28,7 @@ func New(cfg *config.Config, log *logger.Logger) (*Database, error) {
 			Colorful:             true,
 			ParameterizedQueries: true,
 		}),
+		TranslateError: true,
 	})
 
 	if err != nil {
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// errorHandler renders every error returned by a handler as an itserrors.Error, so
+// clients can rely on the error code instead of the message. Errors that are not known
+// are logged with the request correlation ID and answered with a sanitized 500.
+func errorHandler(log *logger.Logger) echo.HTTPErrorHandler {
+	return func(err error, pctx echo.Context) {
+		if pctx.Response().Committed {
+			return
+		}
+
+		body := render(err)
+		body.CorrelationID = pctx.Response().Header().Get(echo.HeaderXRequestID)
+		if body.HTTPStatus >= http.StatusInternalServerError {
+			log.WithField("correlation_id", body.CorrelationID).Errorf("Error handling %s %s: %+v", pctx.Request().Method, pctx.Path(), err)
+		}
+
+		if pctx.Request().Method == http.MethodHead {
+			err = pctx.NoContent(body.HTTPStatus)
+		} else {
+			err = pctx.JSON(body.HTTPStatus, body)
+		}
+		if err != nil {
+			log.Errorf("Error writing error response: %+v", err)
+		}
+	}
+}
+
+func render(err error) itserrors.Error {
+	var (
+		itsErr        itserrors.Error
+		validationErr validator.ValidationErrors
+		httpErr       *echo.HTTPError
+	)
+	switch {
+	case errors.As(err, &itsErr):
+		return itsErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return itserrors.ErrNotFound
+	case errors.As(err, &validationErr):
+		return itserrors.ErrValidation.WithDetails(fieldErrors(validationErr))
+	case errors.As(err, &httpErr):
+		switch httpErr.Code {
+		case http.StatusNotFound:
+			return itserrors.ErrRouteNotFound
+		case http.StatusMethodNotAllowed:
+			return itserrors.ErrMethodNotAllowed
+		case http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusRequestEntityTooLarge:
+			return itserrors.ErrInvalidPayload.WithMessage(fmt.Sprint(httpErr.Message))
+		}
+	}
+	return itserrors.ErrInternal
+}
+
+func fieldErrors(errs validator.ValidationErrors) []itserrors.FieldError {
+	fields := make([]itserrors.FieldError, 0, len(errs))
+	for _, err := range errs {
+		field := err.Namespace()
+		// Drop the struct name, clients only know the payload fields
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		message := fmt.Sprintf("%s failed on the %s rule", field, err.Tag())
+		if err.Param() != "" {
+			message = fmt.Sprintf("%s failed on the %s=%s rule", field, err.Tag(), err.Param())
+		}
+		fields = append(fields, itserrors.FieldError{Field: field, Rule: err.Tag(), Message: message})
+	}
+	return fields
+}
@@ -23,8 +23,10 @@ type Server struct {
 func New(lc fx.Lifecycle, cfg *config.Config, log *logger.Logger) *Server {
 	e := echo.New()
 	e.HideBanner = true
+	e.HTTPErrorHandler = errorHandler(log)
 
 	// Middleware
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -25,7 +25,7 @@ const docTemplate = `{
     "paths": {
         "/call": {
             "post": {
-                "description": "Request a method from a provider or a group of providers and return the first response received",
+                "description": "Request a method from a provider or a group of providers and return the first response received.\nThe newest version of the method matching the version constraint is called, the latest stable\nversion by default, and reported on the envelope.\nSending the token of an ongoing exchange runs its next round instead.\nWith async=true the call runs in background and a job is returned at once, its outcome is polled\non /call/{id}.",
                 "consumes": [
                     "application/json"
                 ],
@@ -45,13 +45,32 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.CallRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Run the call in background and return a job",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Envelope"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.Job"
                         }
                     },
                     "400": {
@@ -60,24 +79,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/itserrors.Error"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
                     }
                 }
             }
         },
-        "/method": {
+        "/call/{id}": {
             "get": {
-                "description": "List methods",
+                "description": "Get the status of an asynchronous call of the user, with its envelope once done or its error once failed.\nJobs expire after the job TTL since their last change.",
                 "consumes": [
                     "application/json"
                 ],
@@ -85,21 +122,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "method"
+                    "orquestrator"
+                ],
+                "summary": "Get an asynchronous call",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "List methods",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Method"
-                            }
+                            "$ref": "#/definitions/model.Job"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/itserrors.Error"
                         }
@@ -118,8 +168,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
-                "description": "Create a new method",
+            "delete": {
+                "description": "Cancel an asynchronous call of the user that did not finish yet.",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,29 +177,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "method"
+                    "orquestrator"
                 ],
-                "summary": "Create a new method",
+                "summary": "Cancel an asynchronous call",
                 "parameters": [
                     {
-                        "description": "payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Method"
-                        }
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Method"
+                            "$ref": "#/definitions/model.Job"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/itserrors.Error"
                         }
@@ -160,6 +215,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -169,9 +230,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/method/{method}": {
+        "/health/providers": {
             "get": {
-                "description": "Get method",
+                "description": "List the health of every provider of the federation",
                 "consumes": [
                     "application/json"
                 ],
@@ -179,35 +240,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "method"
-                ],
-                "summary": "Get method",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Method",
-                        "name": "method",
-                        "in": "path",
-                        "required": true
-                    }
+                    "health"
                 ],
+                "summary": "List providers health",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Method"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/itserrors.Error"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/itserrors.Error"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ProviderHealth"
+                            }
                         }
                     },
                     "500": {
@@ -219,9 +262,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/provider": {
-            "post": {
-                "description": "Create a new provider",
+        "/method": {
+            "get": {
+                "description": "List methods",
                 "consumes": [
                     "application/json"
                 ],
@@ -229,25 +272,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "provider"
-                ],
-                "summary": "Create a new provider",
-                "parameters": [
-                    {
-                        "description": "Provider",
-                        "name": "provider",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Provider"
-                        }
-                    }
+                    "method"
                 ],
+                "summary": "List methods",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Provider"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Method"
+                            }
                         }
                     },
                     "400": {
@@ -269,11 +304,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/provider/list/{method}": {
-            "get": {
-                "description": "List providers",
+            },
+            "post": {
+                "description": "Create a new method. Only federation admins may create methods.",
                 "consumes": [
                     "application/json"
                 ],
@@ -281,23 +314,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "provider"
+                    "method"
                 ],
-                "summary": "List providers",
+                "summary": "Create a new method",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Provider method",
-                        "name": "method",
-                        "in": "path",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Method"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Provider"
+                            "$ref": "#/definitions/model.Method"
                         }
                     },
                     "400": {
@@ -306,6 +348,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -321,9 +375,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/provider/{slug}": {
+        "/method/{method}": {
             "get": {
-                "description": "Get a provider",
+                "description": "Get method",
                 "consumes": [
                     "application/json"
                 ],
@@ -331,23 +385,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "provider"
+                    "method"
                 ],
-                "summary": "Get a provider",
+                "summary": "Get method",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Provider slug",
-                        "name": "slug",
+                        "description": "Method",
+                        "name": "method",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Provider"
+                            "$ref": "#/definitions/model.Method"
                         }
                     },
                     "400": {
@@ -371,7 +432,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete a provider",
+                "description": "Delete a method that no provider is enrolled in. Only federation admins may delete methods.",
                 "consumes": [
                     "application/json"
                 ],
@@ -379,27 +440,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "provider"
+                    "method"
                 ],
-                "summary": "Delete a provider",
+                "summary": "Delete a method",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Provider slug",
-                        "name": "slug",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/model.Provider"
+                            "$ref": "#/definitions/itserrors.Error"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/itserrors.Error"
                         }
@@ -410,6 +488,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -419,7 +503,7 @@ const docTemplate = `{
                 }
             },
             "patch": {
-                "description": "Update a provider",
+                "description": "Replace the fields of a method that are set on the payload, its name never changes. Cached results\nof the method are removed. Only federation admins may update methods.",
                 "consumes": [
                     "application/json"
                 ],
@@ -427,39 +511,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "provider"
+                    "method"
                 ],
-                "summary": "Update a provider",
+                "summary": "Update a method",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Provider slug",
-                        "name": "slug",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Provider",
-                        "name": "provider",
+                        "description": "payload",
+                        "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Provider"
+                            "$ref": "#/definitions/model.MethodUpdate"
                         }
                     },
                     {
                         "type": "string",
-                        "description": "Signature",
-                        "name": "X-Signature",
-                        "in": "header",
-                        "required": true
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.Provider"
+                            "$ref": "#/definitions/model.Method"
                         }
                     },
                     "400": {
@@ -468,6 +559,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/itserrors.Error"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -482,41 +585,2060 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handler.CallRequest": {
-            "type": "object",
-            "properties": {
-                "method": {
-                    "type": "string"
-                },
-                "params": {
-                    "type": "array",
-                    "items": {}
-                }
-            }
         },
-        "handler.Provider": {
-            "type": "object"
-        },
-        "itserrors.Error": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "string"
+        "/method/{method}/cache": {
+            "delete": {
+                "description": "Remove every cached result of a method. Only federation admins may flush caches.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "method"
+                ],
+                "summary": "Flush method cache",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CacheInvalidationResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/method/{method}/cache/invalidate": {
+            "post": {
+                "description": "Remove the cached results of a method for the given params, for every user. Only federation\nadmins may invalidate caches.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "method"
+                ],
+                "summary": "Invalidate a cached call",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CacheInvalidation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CacheInvalidationResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/method/{method}/deprecate": {
+            "post": {
+                "description": "Deprecate a method, every call reports the deprecation on its envelope. Once the sunset date is\nreached the method can no longer be called. Only federation admins may deprecate methods.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "method"
+                ],
+                "summary": "Deprecate a method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MethodDeprecation"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Method"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/method/{method}/versions": {
+            "get": {
+                "description": "List every version of a method, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "method"
+                ],
+                "summary": "List method versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Method"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider": {
+            "post": {
+                "description": "Create a new provider, pending until a federation admin approves it. Only provider operators\nand federation admins may create providers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Create a new provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/list/{method}": {
+            "get": {
+                "description": "List providers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "List providers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}": {
+            "get": {
+                "description": "Get a provider",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Get a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a provider",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Delete a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update a provider",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Update a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the request: t=\u003cunix\u003e,id=\u003cnonce\u003e,v1=\u003chmac\u003e",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Provider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/approve": {
+            "post": {
+                "description": "Allow a pending provider to receive user traffic. Only federation admins may approve providers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Approve a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/breaker": {
+            "get": {
+                "description": "Get the circuit breaker state of a provider and its latest transitions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Get provider circuit breaker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BreakerState"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/health": {
+            "get": {
+                "description": "Get the health of a provider computed from its latest probes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get provider health",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderHealth"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/methods": {
+            "get": {
+                "description": "List the methods a provider is enrolled in",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "List provider methods",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Method"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/methods/{method}": {
+            "post": {
+                "description": "Enroll a provider in a method so it starts receiving calls of that method.\nThe optional payload overrides the method call policy for this provider and sets the weight,\npriority and cost the selection strategy of the method orders providers by.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Enroll a provider in a method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Enrollment options",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.Enrollment"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the request: t=\u003cunix\u003e,id=\u003cnonce\u003e,v1=\u003chmac\u003e",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.MethodProvider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Unenroll a provider from a method so it stops receiving calls of that method",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Unenroll a provider from a method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the request: t=\u003cunix\u003e,id=\u003cnonce\u003e,v1=\u003chmac\u003e",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "^1.2.0",
+                        "description": "Version constraint, the latest stable version by default",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/reject": {
+            "post": {
+                "description": "Stop a provider from receiving user traffic. Only federation admins may reject providers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Reject a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Provider"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/provider/{slug}/secret/rotate": {
+            "post": {
+                "description": "Generate a new secret for the provider and return it, it is not shown again.\nThe previous secret stays valid, for signatures in both directions, until previous_valid_until.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider"
+                ],
+                "summary": "Rotate the secret of a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature of the request: t=\u003cunix\u003e,id=\u003cnonce\u003e,v1=\u003chmac\u003e",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SecretRotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "description": "Register a new user. The API key is only returned on this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserCredentials"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/me": {
+            "get": {
+                "description": "Get the user that owns the API key of the request",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get the authenticated user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/me/consent": {
+            "get": {
+                "description": "List every consent granted by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consent"
+                ],
+                "summary": "List consents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Consent"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Allow a provider to receive the authenticated user's calls of a method",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consent"
+                ],
+                "summary": "Grant consent to a provider",
+                "parameters": [
+                    {
+                        "description": "Consent",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ConsentGrant"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Consent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/me/consent/{provider}/{method}": {
+            "delete": {
+                "description": "Stop a provider from receiving the authenticated user's calls of a method",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "consent"
+                ],
+                "summary": "Revoke consent from a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider slug",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Method",
+                        "name": "method",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{ref}/role": {
+            "put": {
+                "description": "Change the role of a user. Only federation admins may assign roles.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Assign a role to a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User reference",
+                        "name": "ref",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RoleAssignment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/itserrors.Error"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handler.CacheInvalidation": {
+            "type": "object",
+            "properties": {
+                "params": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "handler.CacheInvalidationResult": {
+            "type": "object",
+            "properties": {
+                "removed": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handler.CallRequest": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {}
+                },
+                "token": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is a constraint on the version of the method, the latest stable version by default",
+                    "type": "string",
+                    "example": "^1.2.0"
+                }
+            }
+        },
+        "handler.Provider": {
+            "type": "object"
+        },
+        "itserrors.Error": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "correlation_id": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "http_status": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "retryable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.BreakerState": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "provider-slug"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "closed"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BreakerTransition"
+                    }
+                },
+                "trials": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "model.BreakerTransition": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "closed"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "5 consecutive failures"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "open"
+                }
+            }
+        },
+        "model.CallPolicyOverride": {
+            "type": "object",
+            "properties": {
+                "backoff_ms": {
+                    "type": "integer",
+                    "maximum": 60000,
+                    "minimum": 0,
+                    "example": 250
+                },
+                "backoff_policy": {
+                    "type": "string",
+                    "enum": [
+                        "constant",
+                        "exponential"
+                    ],
+                    "example": "constant"
+                },
+                "retries": {
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 1
+                },
+                "timeout_ms": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 10000
+                }
+            }
+        },
+        "model.ConsensusReport": {
+            "type": "object",
+            "properties": {
+                "agreeing": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Provider A",
+                        "Provider B"
+                    ]
                 },
-                "http_status": {
-                    "type": "integer"
+                "agreement": {
+                    "type": "number",
+                    "example": 0.67
+                },
+                "dissenting": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Provider C"
+                    ]
+                },
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Provider D"
+                    ]
+                },
+                "quorum": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "model.Consent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "MethodName"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "provider-slug"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ConsentGrant": {
+            "type": "object",
+            "required": [
+                "method",
+                "provider"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2030-01-01T00:00:00Z"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "MethodName"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "provider-slug"
+                }
+            }
+        },
+        "model.Deprecation": {
+            "type": "object",
+            "properties": {
+                "deprecated_at": {
+                    "type": "string",
+                    "example": "2023-06-01T00:00:00Z"
                 },
                 "message": {
+                    "type": "string",
+                    "example": "Use OtherMethod instead"
+                },
+                "sunset_at": {
+                    "type": "string",
+                    "example": "2023-09-01T00:00:00Z"
+                }
+            }
+        },
+        "model.Enrollment": {
+            "type": "object",
+            "properties": {
+                "backoff_ms": {
+                    "type": "integer",
+                    "maximum": 60000,
+                    "minimum": 0,
+                    "example": 250
+                },
+                "backoff_policy": {
+                    "type": "string",
+                    "enum": [
+                        "constant",
+                        "exponential"
+                    ],
+                    "example": "constant"
+                },
+                "cost": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 0.01
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "retries": {
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 1
+                },
+                "timeout_ms": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 10000
+                },
+                "weight": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                }
+            }
+        },
+        "model.Envelope": {
+            "type": "object",
+            "properties": {
+                "cache": {
+                    "type": "string",
+                    "example": "miss"
+                },
+                "consensus": {
+                    "description": "Consensus is set on the answer of a consensus method",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ConsensusReport"
+                        }
+                    ]
+                },
+                "deprecation": {
+                    "description": "Deprecation is set when the called method is deprecated",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Deprecation"
+                        }
+                    ]
+                },
+                "error": {
+                    "$ref": "#/definitions/itserrors.Error"
+                },
+                "exchange": {
+                    "$ref": "#/definitions/model.ExchangeStep"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "result": {},
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Envelope"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "version": {
                     "type": "string"
                 }
             }
         },
+        "model.ExchangeStep": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "step": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "token": {
+                    "type": "string",
+                    "example": "3f1c0c3e5d6b4a3f9e1b2c7d8a9f0e1d"
+                }
+            }
+        },
+        "model.Job": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2023-06-01T12:00:00Z"
+                },
+                "error": {
+                    "$ref": "#/definitions/itserrors.Error"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9b2f4c1d7e3a4b6c8d0e1f2a3b4c5d6e"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "MethodName"
+                },
+                "result": {
+                    "$ref": "#/definitions/model.Envelope"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "running",
+                        "done",
+                        "failed",
+                        "canceled"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.JobStatus"
+                        }
+                    ],
+                    "example": "running"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2023-06-01T12:00:05Z"
+                }
+            }
+        },
+        "model.JobStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "done",
+                "failed",
+                "canceled"
+            ],
+            "x-enum-varnames": [
+                "JobPending",
+                "JobRunning",
+                "JobDone",
+                "JobFailed",
+                "JobCanceled"
+            ]
+        },
+        "model.MergeRule": {
+            "type": "object",
+            "required": [
+                "field",
+                "strategy"
+            ],
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "route.steps"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "id"
+                },
+                "strategy": {
+                    "enum": [
+                        "concat",
+                        "dedupe",
+                        "prefer_non_empty",
+                        "min",
+                        "max",
+                        "avg"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MergeStrategy"
+                        }
+                    ],
+                    "example": "dedupe"
+                }
+            }
+        },
+        "model.MergeStrategy": {
+            "type": "string",
+            "enum": [
+                "concat",
+                "dedupe",
+                "prefer_non_empty",
+                "min",
+                "max",
+                "avg"
+            ],
+            "x-enum-varnames": [
+                "MergeConcat",
+                "MergeDedupe",
+                "MergePreferNonEmpty",
+                "MergeMin",
+                "MergeMax",
+                "MergeAvg"
+            ]
+        },
         "model.Method": {
             "type": "object"
         },
+        "model.MethodDeprecation": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Use OtherMethod instead"
+                },
+                "sunset_at": {
+                    "type": "string",
+                    "example": "2023-09-01T00:00:00Z"
+                }
+            }
+        },
+        "model.MethodKind": {
+            "type": "string",
+            "enum": [
+                "broadcast",
+                "concurrent",
+                "indepotent",
+                "exchange",
+                "hedged",
+                "consensus"
+            ],
+            "x-enum-varnames": [
+                "Broadcast",
+                "Concurrent",
+                "Indepotent",
+                "Exchange",
+                "Hedged",
+                "Consensus"
+            ]
+        },
+        "model.MethodProvider": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "type": "number",
+                    "example": 0.01
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "method": {
+                    "$ref": "#/definitions/model.Method"
+                },
+                "method_id": {
+                    "type": "integer"
+                },
+                "overrides": {
+                    "$ref": "#/definitions/model.CallPolicyOverride"
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "provider_id": {
+                    "type": "integer"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.MethodUpdate": {
+            "type": "object",
+            "properties": {
+                "backoff_ms": {
+                    "type": "integer",
+                    "maximum": 60000,
+                    "minimum": 0,
+                    "example": 250
+                },
+                "backoff_policy": {
+                    "type": "string",
+                    "enum": [
+                        "constant",
+                        "exponential"
+                    ],
+                    "example": "constant"
+                },
+                "cache_per_user": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "cache_ttl": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "consensus_keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "closed",
+                        "reason"
+                    ]
+                },
+                "consensus_quorum": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "description": {
+                    "type": "string",
+                    "example": "This method does an operation"
+                },
+                "hedge_delay_ms": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "kind": {
+                    "enum": [
+                        "broadcast",
+                        "concurrent",
+                        "indepotent",
+                        "exchange",
+                        "hedged",
+                        "consensus"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MethodKind"
+                        }
+                    ],
+                    "example": "concurrent"
+                },
+                "merge_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MergeRule"
+                    }
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "string",
+                        "string",
+                        "int"
+                    ]
+                },
+                "result_structure": {
+                    "$ref": "#/definitions/model.ResultStructure"
+                },
+                "retries": {
+                    "type": "integer",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 1
+                },
+                "selection": {
+                    "enum": [
+                        "ordered",
+                        "round_robin",
+                        "weighted",
+                        "lowest_latency",
+                        "cheapest",
+                        "priority",
+                        "random"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.SelectionStrategy"
+                        }
+                    ],
+                    "example": "lowest_latency"
+                },
+                "selection_limit": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "timeout_ms": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 10000
+                }
+            }
+        },
         "model.Provider": {
             "type": "object",
             "required": [
@@ -530,6 +2652,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "some@email.com"
                 },
+                "health_url": {
+                    "type": "string",
+                    "example": "https://provider.com/health"
+                },
                 "name": {
                     "type": "string",
                     "example": "Example LTDA"
@@ -541,15 +2667,198 @@ const docTemplate = `{
                     "type": "string",
                     "example": "provider-slug"
                 },
+                "status": {
+                    "description": "Providers registered before the approval workflow existed stay approved, new ones\nare created pending",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ProviderStatus"
+                        }
+                    ],
+                    "example": "pending"
+                },
                 "webhook": {
                     "type": "string",
                     "example": "https://provider.com/webhook"
                 }
             }
         },
+        "model.ProviderHealth": {
+            "type": "object",
+            "properties": {
+                "avg_latency_ms": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "last_check": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "provider-slug"
+                },
+                "samples": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                },
+                "success_rate": {
+                    "type": "number",
+                    "example": 0.95
+                }
+            }
+        },
+        "model.ProviderStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "approved",
+                "rejected"
+            ],
+            "x-enum-varnames": [
+                "ProviderPending",
+                "ProviderApproved",
+                "ProviderRejected"
+            ]
+        },
         "model.ResultStructure": {
             "type": "object",
             "additionalProperties": {}
+        },
+        "model.Role": {
+            "type": "string",
+            "enum": [
+                "federation_admin",
+                "provider_operator",
+                "consumer"
+            ],
+            "x-enum-varnames": [
+                "RoleFederationAdmin",
+                "RoleProviderOperator",
+                "RoleConsumer"
+            ]
+        },
+        "model.RoleAssignment": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "enum": [
+                        "federation_admin",
+                        "provider_operator",
+                        "consumer"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    ],
+                    "example": "provider_operator"
+                }
+            }
+        },
+        "model.SecretRotation": {
+            "type": "object",
+            "properties": {
+                "previous_valid_until": {
+                    "type": "string",
+                    "example": "2023-06-16T12:00:00Z"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "5f2b6c0e9d..."
+                }
+            }
+        },
+        "model.SelectionStrategy": {
+            "type": "string",
+            "enum": [
+                "ordered",
+                "round_robin",
+                "weighted",
+                "lowest_latency",
+                "cheapest",
+                "priority",
+                "random"
+            ],
+            "x-enum-varnames": [
+                "SelectionOrdered",
+                "SelectionRoundRobin",
+                "SelectionWeighted",
+                "SelectionLowestLatency",
+                "SelectionCheapest",
+                "SelectionPriority",
+                "SelectionRandom"
+            ]
+        },
+        "model.User": {
+            "type": "object",
+            "required": [
+                "email",
+                "name"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@email.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "ref": {
+                    "type": "string",
+                    "example": "5b0f1a2e9c4d4e7f8a1b2c3d4e5f6a7b"
+                },
+                "role": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    ],
+                    "example": "consumer"
+                }
+            }
+        },
+        "model.UserCredentials": {
+            "type": "object",
+            "required": [
+                "email",
+                "name"
+            ],
+            "properties": {
+                "api_key": {
+                    "type": "string",
+                    "example": "fedits_4f2c..."
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@email.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "ref": {
+                    "type": "string",
+                    "example": "5b0f1a2e9c4d4e7f8a1b2c3d4e5f6a7b"
+                },
+                "role": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Role"
+                        }
+                    ],
+                    "example": "consumer"
+                }
+            }
         }
     }
 }`
@@ -7,8 +7,10 @@ import os
 import logging
 import hmac
 import hashlib
+import time
 
 app = Flask(__name__)
+seen_nonces = set()
 secret = os.getenv('HASH_SECRET')
 api_key = os.getenv('OPEN_ROUTE_SERVICE_API_KEY')
 
@@ -17,6 +19,35 @@ def parse_coordinates(coord_str):
     return list(map(float, coord_str.split(',')))
 
 
+def verify_signature(header, method, body, tolerance=300):
+    """Verify the FED ITS signature header: t=<unix>,id=<nonce>,v1=<hmac>[,v1=<hmac>]"""
+    if not header:
+        return False
+    fields = {}
+    signatures = []
+    for part in header.split(','):
+        key, _, value = part.strip().partition('=')
+        if key == 'v1':
+            signatures.append(value)
+        else:
+            fields[key] = value
+    try:
+        timestamp = int(fields['t'])
+        nonce = fields['id']
+    except (KeyError, ValueError):
+        return False
+    if abs(time.time() - timestamp) > tolerance:
+        return False
+    if nonce in seen_nonces:
+        return False
+    content = f'{timestamp}.{nonce}.{method}.'.encode() + body
+    expected = hmac.new(secret.encode(), content, hashlib.sha256).hexdigest()
+    if not any(hmac.compare_digest(expected, candidate) for candidate in signatures):
+        return False
+    seen_nonces.add(nonce)
+    return True
+
+
 @app.route('/route', methods=['POST'])
 def get_route():
     data = request.json
@@ -26,14 +57,9 @@ def get_route():
 
     app.logger.info('Received request with start: %s, end: %s', start, end)
 
-    # Calculate the HMAC
-    hmac_obj = hmac.new(secret.encode(), json.dumps(
-        data).encode(), hashlib.sha256)
-    calculated_signature = hmac_obj.hexdigest()
-
-    # Compare the provided signature and the calculated signature
-    # if signature != calculated_signature:
-    #     return jsonify({'status': 'Signatures do not match.'}), 400
+    # Verify the request was signed by FED ITS and is not a replay
+    if not verify_signature(signature, data.get('method'), request.get_data()):
+        return jsonify({'error': {'code': 'INVALID_SIGNATURE', 'message': 'Signatures do not match.', 'retryable': False}}), 400
 
     # Validate the request
     if not start or not end:
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/caioeverest/fed-its/service"
+	"github.com/labstack/echo/v4"
+)
+
+type Breaker struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service service.BreakerI
+}
+
+func NewBreaker(cfg *config.Config, log *logger.Logger, service service.BreakerI) *Breaker {
+	handler := &Breaker{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+	return handler
+}
+
+// Get godoc
+// @Summary Get provider circuit breaker
+// @Description Get the circuit breaker state of a provider and its latest transitions
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Success 200 {object} model.BreakerState
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/breaker [get]
+func (b *Breaker) Get(pctx echo.Context) (err error) {
+	var (
+		result model.BreakerState
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+	)
+
+	if result, err = b.service.Get(ctx, slug); err != nil {
+		b.log.Errorf("Error getting provider circuit breaker: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
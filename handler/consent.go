@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/caioeverest/fed-its/service"
+	"github.com/labstack/echo/v4"
+)
+
+type Consent struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service service.ConsentI
+}
+
+func NewConsent(cfg *config.Config, log *logger.Logger, service service.ConsentI) *Consent {
+	handler := &Consent{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+	return handler
+}
+
+// Grant godoc
+// @Summary Grant consent to a provider
+// @Description Allow a provider to receive the authenticated user's calls of a method
+// @Tags consent
+// @Accept json
+// @Produce json
+// @Param payload body model.ConsentGrant true "Consent"
+// @Param X-API-Key header string true "API key"
+// @Success 201 {object} model.Consent
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user/me/consent [post]
+func (c *Consent) Grant(pctx echo.Context) (err error) {
+	var (
+		payload model.ConsentGrant
+		result  model.Consent
+		ctx     = pctx.Request().Context()
+		user    = currentUser(pctx)
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		c.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+
+	if result, err = c.service.Grant(ctx, user, payload); err != nil {
+		c.log.Errorf("Error granting consent: %v", err)
+		return
+	}
+
+	return pctx.JSON(201, result)
+}
+
+// List godoc
+// @Summary List consents
+// @Description List every consent granted by the authenticated user
+// @Tags consent
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Success 200 {array} model.Consent
+// @Failure      401  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user/me/consent [get]
+func (c *Consent) List(pctx echo.Context) (err error) {
+	var (
+		result []model.Consent
+		ctx    = pctx.Request().Context()
+		user   = currentUser(pctx)
+	)
+
+	if result, err = c.service.List(ctx, user); err != nil {
+		c.log.Errorf("Error listing consents: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Revoke godoc
+// @Summary Revoke consent from a provider
+// @Description Stop a provider from receiving the authenticated user's calls of a method
+// @Tags consent
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider slug"
+// @Param method path string true "Method"
+// @Param X-API-Key header string true "API key"
+// @Success 200
+// @Failure      401  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user/me/consent/{provider}/{method} [delete]
+func (c *Consent) Revoke(pctx echo.Context) (err error) {
+	var (
+		ctx      = pctx.Request().Context()
+		user     = currentUser(pctx)
+		provider = pctx.Param("provider")
+		method   = pctx.Param("method")
+	)
+
+	if err = c.service.Revoke(ctx, user, provider, method); err != nil {
+		c.log.Errorf("Error revoking consent: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, nil)
+}
@@ -7,6 +7,10 @@ func Providers() fx.Option {
 		NewProvider,
 		NewMethod,
 		NewOrquestrator,
+		NewUser,
+		NewConsent,
+		NewHealth,
+		NewBreaker,
 	)
 }
 
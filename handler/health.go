@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/caioeverest/fed-its/service"
+	"github.com/labstack/echo/v4"
+)
+
+type Health struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service service.HealthI
+}
+
+func NewHealth(cfg *config.Config, log *logger.Logger, service service.HealthI) *Health {
+	handler := &Health{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+	return handler
+}
+
+// Get godoc
+// @Summary Get provider health
+// @Description Get the health of a provider computed from its latest probes
+// @Tags health
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Success 200 {object} model.ProviderHealth
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/health [get]
+func (h *Health) Get(pctx echo.Context) (err error) {
+	var (
+		result model.ProviderHealth
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+	)
+
+	if result, err = h.service.Get(ctx, slug); err != nil {
+		h.log.Errorf("Error getting provider health: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// List godoc
+// @Summary List providers health
+// @Description List the health of every provider of the federation
+// @Tags health
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.ProviderHealth
+// @Failure      500  {object}  itserrors.Error
+// @Router /health/providers [get]
+func (h *Health) List(pctx echo.Context) (err error) {
+	var (
+		result []model.ProviderHealth
+		ctx    = pctx.Request().Context()
+	)
+
+	if result, err = h.service.List(ctx); err != nil {
+		h.log.Errorf("Error listing providers health: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
@@ -8,6 +8,14 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+type CacheInvalidation struct {
+	Params []any `json:"params"`
+}
+
+type CacheInvalidationResult struct {
+	Removed int64 `json:"removed" example:"1"`
+}
+
 type Method struct {
 	cfg     *config.Config
 	log     *logger.Logger
@@ -25,13 +33,16 @@ func NewMethod(cfg *config.Config, log *logger.Logger, service service.MethodI)
 
 // Create godoc
 // @Summary Create a new method
-// @Description Create a new method
+// @Description Create a new method. Only federation admins may create methods.
 // @Tags method
 // @Accept json
 // @Produce json
+// @Param X-API-Key header string true "API key"
 // @Param payload body model.Method true "payload"
 // @Success 200 {object} model.Method
 // @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
 // @Failure      404  {object}  itserrors.Error
 // @Failure      500  {object}  itserrors.Error
 // @Router /method [post]
@@ -87,6 +98,7 @@ func (m *Method) List(pctx echo.Context) (err error) {
 // @Accept json
 // @Produce json
 // @Param method path string true "Method"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
 // @Success 200 {object} model.Method
 // @Failure      400  {object}  itserrors.Error
 // @Failure      404  {object}  itserrors.Error
@@ -94,15 +106,225 @@ func (m *Method) List(pctx echo.Context) (err error) {
 // @Router /method/{method} [get]
 func (m *Method) Get(pctx echo.Context) (err error) {
 	var (
-		result model.Method
+		result  model.Method
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+		version = pctx.QueryParam("version")
+	)
+
+	if result, err = m.service.Get(ctx, method, version); err != nil {
+		m.log.Errorf("Error getting method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Versions godoc
+// @Summary List method versions
+// @Description List every version of a method, newest first
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param method path string true "Method"
+// @Success 200 {array} model.Method
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method}/versions [get]
+func (m *Method) Versions(pctx echo.Context) (err error) {
+	var (
+		result []model.Method
 		method = pctx.Param("method")
 		ctx    = pctx.Request().Context()
 	)
 
-	if result, err = m.service.Get(ctx, method); err != nil {
-		m.log.Errorf("Error getting method %s: %v", method, err)
+	if result, err = m.service.Versions(ctx, method); err != nil {
+		m.log.Errorf("Error listing versions of method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Update godoc
+// @Summary Update a method
+// @Description Replace the fields of a method that are set on the payload, its name never changes. Cached results
+// @Description of the method are removed. Only federation admins may update methods.
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param method path string true "Method"
+// @Param payload body model.MethodUpdate true "payload"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
+// @Success 200 {object} model.Method
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method} [patch]
+func (m *Method) Update(pctx echo.Context) (err error) {
+	var (
+		payload model.MethodUpdate
+		result  model.Method
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+		version = pctx.QueryParam("version")
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		m.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+
+	if result, err = m.service.Update(ctx, method, version, payload); err != nil {
+		m.log.Errorf("Error updating method %s: %v", method, err)
 		return
 	}
 
 	return pctx.JSON(200, result)
 }
+
+// Deprecate godoc
+// @Summary Deprecate a method
+// @Description Deprecate a method, every call reports the deprecation on its envelope. Once the sunset date is
+// @Description reached the method can no longer be called. Only federation admins may deprecate methods.
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param method path string true "Method"
+// @Param payload body model.MethodDeprecation true "payload"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
+// @Success 200 {object} model.Method
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method}/deprecate [post]
+func (m *Method) Deprecate(pctx echo.Context) (err error) {
+	var (
+		payload model.MethodDeprecation
+		result  model.Method
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+		version = pctx.QueryParam("version")
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		m.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+
+	if result, err = m.service.Deprecate(ctx, method, version, payload); err != nil {
+		m.log.Errorf("Error deprecating method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Delete godoc
+// @Summary Delete a method
+// @Description Delete a method that no provider is enrolled in. Only federation admins may delete methods.
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param method path string true "Method"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
+// @Success 200
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      409  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method} [delete]
+func (m *Method) Delete(pctx echo.Context) (err error) {
+	var (
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+		version = pctx.QueryParam("version")
+	)
+
+	if err = m.service.Delete(ctx, method, version); err != nil {
+		m.log.Errorf("Error deleting method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, nil)
+}
+
+// FlushCache godoc
+// @Summary Flush method cache
+// @Description Remove every cached result of a method. Only federation admins may flush caches.
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param method path string true "Method"
+// @Success 200 {object} handler.CacheInvalidationResult
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method}/cache [delete]
+func (m *Method) FlushCache(pctx echo.Context) (err error) {
+	var (
+		removed int64
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+	)
+
+	if removed, err = m.service.InvalidateCache(ctx, method, nil); err != nil {
+		m.log.Errorf("Error flushing cache of method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, CacheInvalidationResult{Removed: removed})
+}
+
+// InvalidateCache godoc
+// @Summary Invalidate a cached call
+// @Description Remove the cached results of a method for the given params, for every user. Only federation
+// @Description admins may invalidate caches.
+// @Tags method
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param method path string true "Method"
+// @Param payload body handler.CacheInvalidation true "Payload"
+// @Success 200 {object} handler.CacheInvalidationResult
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /method/{method}/cache/invalidate [post]
+func (m *Method) InvalidateCache(pctx echo.Context) (err error) {
+	var (
+		payload CacheInvalidation
+		removed int64
+		method  = pctx.Param("method")
+		ctx     = pctx.Request().Context()
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		m.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+	if payload.Params == nil {
+		payload.Params = []any{}
+	}
+
+	if removed, err = m.service.InvalidateCache(ctx, method, payload.Params); err != nil {
+		m.log.Errorf("Error invalidating cache of method %s: %v", method, err)
+		return
+	}
+
+	return pctx.JSON(200, CacheInvalidationResult{Removed: removed})
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
 	"github.com/caioeverest/fed-its/internal/logger"
 	"github.com/caioeverest/fed-its/model"
 	"github.com/caioeverest/fed-its/service"
@@ -14,44 +17,144 @@ type Orquestrator struct {
 	conf    *config.Config
 	log     *logger.Logger
 	service service.Orquestrate
+	jobs    service.JobI
 }
 
-func NewOrquestrator(conf *config.Config, log *logger.Logger, service service.Orquestrate) *Orquestrator {
-	return &Orquestrator{conf, log, service}
+func NewOrquestrator(conf *config.Config, log *logger.Logger, service service.Orquestrate, jobs service.JobI) *Orquestrator {
+	return &Orquestrator{conf, log, service, jobs}
 }
 
 type CallRequest struct {
 	Method string `json:"method"`
-	Params []any  `json:"params"`
+	// Version is a constraint on the version of the method, the latest stable version by default
+	Version string `json:"version,omitempty" example:"^1.2.0"`
+	Params  []any  `json:"params"`
+	Token   string `json:"token,omitempty"`
 }
 
 // Request godoc
 // @Summary Request a method
-// @Description Request a method from a provider or a group of providers and return the first response received
+// @Description Request a method from a provider or a group of providers and return the first response received.
+// @Description The newest version of the method matching the version constraint is called, the latest stable
+// @Description version by default, and reported on the envelope.
+// @Description Sending the token of an ongoing exchange runs its next round instead.
+// @Description With async=true the call runs in background and a job is returned at once, its outcome is polled
+// @Description on /call/{id}.
 // @Tags orquestrator
 // @Accept json
 // @Produce json
 // @Param payload body handler.CallRequest true "Payload"
-// @Success 200 {object} string
+// @Param async query bool false "Run the call in background and return a job"
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} model.Envelope
+// @Success 202 {object} model.Job
 // @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
 // @Failure      404  {object}  itserrors.Error
+// @Failure      409  {object}  itserrors.Error
 // @Failure      500  {object}  itserrors.Error
+// @Failure      502  {object}  itserrors.Error
 // @Router /call [post]
 func (o *Orquestrator) Request(pctx echo.Context) (err error) {
 	var (
 		ctx    = pctx.Request().Context()
+		user   = currentUser(pctx)
 		body   CallRequest
+		async  bool
 		result model.Envelope
+		job    model.Job
 	)
 
 	if err = pctx.Bind(&body); err != nil {
 		o.log.Errorf("Error binding payload: %v", err)
 		return
 	}
-	if result, err = o.service.Request(ctx, "", body.Method, body.Params); err != nil {
+	if raw := pctx.QueryParam("async"); raw != "" {
+		if async, err = strconv.ParseBool(raw); err != nil {
+			o.log.Errorf("Error parsing async flag: %v", err)
+			return itserrors.ErrValidation.WithMessage("async must be true or false")
+		}
+	}
+
+	call := func(ctx context.Context) (model.Envelope, error) {
+		if body.Token != "" {
+			return o.service.Continue(ctx, user.Ref, body.Token, body.Params)
+		}
+		return o.service.Request(ctx, user.Ref, body.Method, body.Version, body.Params)
+	}
+
+	if async {
+		if job, err = o.jobs.Submit(ctx, user.Ref, body.Method, call); err != nil {
+			o.log.Errorf("Error submitting job: %+v", err)
+			return
+		}
+		pctx.Response().Header().Set(echo.HeaderLocation, "/call/"+job.ID)
+		return pctx.JSON(http.StatusAccepted, job)
+	}
+	if result, err = call(ctx); err != nil {
 		o.log.Errorf("Error while validating request: %+v", err)
 		return
 	}
 
 	return pctx.JSON(http.StatusOK, result)
 }
+
+// Job godoc
+// @Summary Get an asynchronous call
+// @Description Get the status of an asynchronous call of the user, with its envelope once done or its error once failed.
+// @Description Jobs expire after the job TTL since their last change.
+// @Tags orquestrator
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} model.Job
+// @Failure      401  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /call/{id} [get]
+func (o *Orquestrator) Job(pctx echo.Context) (err error) {
+	var (
+		ctx  = pctx.Request().Context()
+		user = currentUser(pctx)
+		id   = pctx.Param("id")
+		job  model.Job
+	)
+
+	if job, err = o.jobs.Get(ctx, user.Ref, id); err != nil {
+		o.log.Errorf("Error getting job %s: %+v", id, err)
+		return
+	}
+
+	return pctx.JSON(http.StatusOK, job)
+}
+
+// Cancel godoc
+// @Summary Cancel an asynchronous call
+// @Description Cancel an asynchronous call of the user that did not finish yet.
+// @Tags orquestrator
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} model.Job
+// @Failure      401  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      409  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /call/{id} [delete]
+func (o *Orquestrator) Cancel(pctx echo.Context) (err error) {
+	var (
+		ctx  = pctx.Request().Context()
+		user = currentUser(pctx)
+		id   = pctx.Param("id")
+		job  model.Job
+	)
+
+	if job, err = o.jobs.Cancel(ctx, user.Ref, id); err != nil {
+		o.log.Errorf("Error canceling job %s: %+v", id, err)
+		return
+	}
+
+	return pctx.JSON(http.StatusOK, job)
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	"io"
+
 	"github.com/caioeverest/fed-its/internal/config"
 	_ "github.com/caioeverest/fed-its/internal/itserrors"
 	"github.com/caioeverest/fed-its/internal/logger"
 	"github.com/caioeverest/fed-its/model"
 	"github.com/caioeverest/fed-its/service"
+	"github.com/caioeverest/fed-its/signature"
 	"github.com/labstack/echo/v4"
 )
 
@@ -27,13 +31,17 @@ func NewProvider(cfg *config.Config, log *logger.Logger, service service.Provide
 
 // Create godoc
 // @Summary Create a new provider
-// @Description Create a new provider
+// @Description Create a new provider, pending until a federation admin approves it. Only provider operators
+// @Description and federation admins may create providers.
 // @Tags provider
 // @Accept json
 // @Produce json
+// @Param X-API-Key header string true "API key"
 // @Param provider body model.Provider true "Provider"
 // @Success 200 {object} model.Provider
 // @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
 // @Failure      404  {object}  itserrors.Error
 // @Failure      500  {object}  itserrors.Error
 // @Router /provider [post]
@@ -92,7 +100,7 @@ func (p *Provider) Get(pctx echo.Context) (err error) {
 // @Produce json
 // @Param slug path string true "Provider slug"
 // @Param provider body model.Provider true "Provider"
-// @Param X-Signature header string true "Signature"
+// @Param X-Signature header string true "Signature of the request: t=<unix>,id=<nonce>,v1=<hmac>"
 // @Success 200 {object} Provider
 // @Failure      400  {object}  itserrors.Error
 // @Failure      404  {object}  itserrors.Error
@@ -100,18 +108,22 @@ func (p *Provider) Get(pctx echo.Context) (err error) {
 // @Router /provider/{slug} [patch]
 func (p *Provider) Update(pctx echo.Context) (err error) {
 	var (
-		payload   model.Provider
-		result    model.Provider
-		slug      = pctx.Param("slug")
-		ctx       = pctx.Request().Context()
-		signature = pctx.Request().Header.Get("X-Signature")
+		payload model.Provider
+		result  model.Provider
+		slug    = pctx.Param("slug")
+		ctx     = pctx.Request().Context()
+		signed  service.SignedRequest
 	)
 
+	if signed, err = signedRequest(pctx); err != nil {
+		p.log.Errorf("Error reading payload: %v", err)
+		return
+	}
 	if err = pctx.Bind(&payload); err != nil {
 		p.log.Errorf("Error binding payload: %v", err)
 		return
 	}
-	if result, err = p.service.Update(ctx, signature, slug, payload); err != nil {
+	if result, err = p.service.Update(ctx, signed, slug, payload); err != nil {
 		p.log.Errorf("Error update provider: %v", err)
 		return
 	}
@@ -133,12 +145,16 @@ func (p *Provider) Update(pctx echo.Context) (err error) {
 // @Router /provider/{slug} [delete]
 func (p *Provider) Delete(pctx echo.Context) (err error) {
 	var (
-		ctx       = pctx.Request().Context()
-		slug      = pctx.Param("slug")
-		signature = pctx.Request().Header.Get("X-Signature")
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+		signed service.SignedRequest
 	)
 
-	if err = p.service.Delete(ctx, signature, slug); err != nil {
+	if signed, err = signedRequest(pctx); err != nil {
+		p.log.Errorf("Error reading payload: %v", err)
+		return
+	}
+	if err = p.service.Delete(ctx, signed, slug); err != nil {
 		p.log.Errorf("Error delete provider: %v", err)
 		return
 	}
@@ -172,3 +188,225 @@ func (p *Provider) List(pctx echo.Context) (err error) {
 
 	return pctx.JSON(200, result)
 }
+
+// Enroll godoc
+// @Summary Enroll a provider in a method
+// @Description Enroll a provider in a method so it starts receiving calls of that method.
+// @Description The optional payload overrides the method call policy for this provider and sets the weight,
+// @Description priority and cost the selection strategy of the method orders providers by.
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Param method path string true "Method"
+// @Param payload body model.Enrollment false "Enrollment options"
+// @Param X-Signature header string true "Signature of the request: t=<unix>,id=<nonce>,v1=<hmac>"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
+// @Success 201 {object} model.MethodProvider
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/methods/{method} [post]
+func (p *Provider) Enroll(pctx echo.Context) (err error) {
+	var (
+		payload model.Enrollment
+		result  model.MethodProvider
+		ctx     = pctx.Request().Context()
+		slug    = pctx.Param("slug")
+		method  = pctx.Param("method")
+		signed  service.SignedRequest
+		version = pctx.QueryParam("version")
+	)
+
+	if signed, err = signedRequest(pctx); err != nil {
+		p.log.Errorf("Error reading payload: %v", err)
+		return
+	}
+	if err = pctx.Bind(&payload); err != nil {
+		p.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+	if result, err = p.service.Enroll(ctx, signed, slug, method, version, payload); err != nil {
+		p.log.Errorf("Error enroll provider: %v", err)
+		return
+	}
+
+	return pctx.JSON(201, result)
+}
+
+// Unenroll godoc
+// @Summary Unenroll a provider from a method
+// @Description Unenroll a provider from a method so it stops receiving calls of that method
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Param method path string true "Method"
+// @Param X-Signature header string true "Signature of the request: t=<unix>,id=<nonce>,v1=<hmac>"
+// @Param version query string false "Version constraint, the latest stable version by default" example(^1.2.0)
+// @Success 200
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/methods/{method} [delete]
+func (p *Provider) Unenroll(pctx echo.Context) (err error) {
+	var (
+		ctx     = pctx.Request().Context()
+		slug    = pctx.Param("slug")
+		method  = pctx.Param("method")
+		signed  service.SignedRequest
+		version = pctx.QueryParam("version")
+	)
+
+	if signed, err = signedRequest(pctx); err != nil {
+		p.log.Errorf("Error reading payload: %v", err)
+		return
+	}
+	if err = p.service.Unenroll(ctx, signed, slug, method, version); err != nil {
+		p.log.Errorf("Error unenroll provider: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, nil)
+}
+
+// Methods godoc
+// @Summary List provider methods
+// @Description List the methods a provider is enrolled in
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Success 200 {array} model.Method
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/methods [get]
+func (p *Provider) Methods(pctx echo.Context) (err error) {
+	var (
+		result []model.Method
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+	)
+
+	if result, err = p.service.Methods(ctx, slug); err != nil {
+		p.log.Errorf("Error list provider methods: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// RotateSecret godoc
+// @Summary Rotate the secret of a provider
+// @Description Generate a new secret for the provider and return it, it is not shown again.
+// @Description The previous secret stays valid, for signatures in both directions, until previous_valid_until.
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param slug path string true "Provider slug"
+// @Param X-Signature header string true "Signature of the request: t=<unix>,id=<nonce>,v1=<hmac>"
+// @Success 200 {object} model.SecretRotation
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      409  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/secret/rotate [post]
+func (p *Provider) RotateSecret(pctx echo.Context) (err error) {
+	var (
+		result model.SecretRotation
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+		signed service.SignedRequest
+	)
+
+	if signed, err = signedRequest(pctx); err != nil {
+		p.log.Errorf("Error reading payload: %v", err)
+		return
+	}
+	if result, err = p.service.RotateSecret(ctx, signed, slug); err != nil {
+		p.log.Errorf("Error rotate provider secret: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Approve godoc
+// @Summary Approve a provider
+// @Description Allow a pending provider to receive user traffic. Only federation admins may approve providers.
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param slug path string true "Provider slug"
+// @Success 200 {object} model.Provider
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/approve [post]
+func (p *Provider) Approve(pctx echo.Context) (err error) {
+	var (
+		result model.Provider
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+	)
+
+	if result, err = p.service.Approve(ctx, slug); err != nil {
+		p.log.Errorf("Error approve provider: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Reject godoc
+// @Summary Reject a provider
+// @Description Stop a provider from receiving user traffic. Only federation admins may reject providers.
+// @Tags provider
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param slug path string true "Provider slug"
+// @Success 200 {object} model.Provider
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /provider/{slug}/reject [post]
+func (p *Provider) Reject(pctx echo.Context) (err error) {
+	var (
+		result model.Provider
+		ctx    = pctx.Request().Context()
+		slug   = pctx.Param("slug")
+	)
+
+	if result, err = p.service.Reject(ctx, slug); err != nil {
+		p.log.Errorf("Error reject provider: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// signedRequest reads what a provider signed on a management call and restores the
+// body so it can still be bound
+func signedRequest(pctx echo.Context) (signed service.SignedRequest, err error) {
+	var (
+		request = pctx.Request()
+		body    []byte
+	)
+	if request.Body != nil {
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return service.SignedRequest{
+		Header: request.Header.Get(signature.Header),
+		Method: request.Method,
+		URI:    request.URL.RequestURI(),
+		Body:   body,
+	}, nil
+}
@@ -4,35 +4,79 @@ import (
 	"context"
 
 	"github.com/caioeverest/fed-its/adapter/http"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/labstack/echo/v4"
 	"go.uber.org/fx"
 )
 
 // NewRouter creates a new router
-func NewRouter(lc fx.Lifecycle, server *http.Server, providerHandler *Provider, methodHandler *Method, orquestratorHandler *Orquestrator) {
+func NewRouter(lc fx.Lifecycle, server *http.Server, providerHandler *Provider, methodHandler *Method, orquestratorHandler *Orquestrator, userHandler *User, consentHandler *Consent, healthHandler *Health, breakerHandler *Breaker) {
+	var (
+		admin    = []echo.MiddlewareFunc{userHandler.Authenticate, userHandler.Require(model.RoleFederationAdmin)}
+		operator = []echo.MiddlewareFunc{userHandler.Authenticate, userHandler.Require(model.RoleProviderOperator, model.RoleFederationAdmin)}
+	)
 	lc.Append(
 		fx.Hook{
 			OnStart: func(ctx context.Context) error {
 				// Provider
 				{
 					router := server.Group("/provider")
-					router.POST("", providerHandler.Create)
+					router.POST("", providerHandler.Create, operator...)
 					router.GET("/:slug", providerHandler.Get)
 					router.PATCH("/:slug", providerHandler.Update)
 					router.DELETE("/:slug", providerHandler.Delete)
+					router.POST("/:slug/secret/rotate", providerHandler.RotateSecret)
+					router.POST("/:slug/approve", providerHandler.Approve, admin...)
+					router.POST("/:slug/reject", providerHandler.Reject, admin...)
 					router.GET("/list/:method", providerHandler.List)
+					router.GET("/:slug/health", healthHandler.Get)
+					router.GET("/:slug/breaker", breakerHandler.Get)
+					router.GET("/:slug/methods", providerHandler.Methods)
+					router.POST("/:slug/methods/:method", providerHandler.Enroll)
+					router.DELETE("/:slug/methods/:method", providerHandler.Unenroll)
+				}
+
+				// Health
+				{
+					server.GET("/health/providers", healthHandler.List)
 				}
 
 				// Method
 				{
 					router := server.Group("/method")
-					router.POST("", methodHandler.Create)
+					router.POST("", methodHandler.Create, admin...)
 					router.GET("", methodHandler.List)
 					router.GET("/:method", methodHandler.Get)
+					router.GET("/:method/versions", methodHandler.Versions)
+					router.PATCH("/:method", methodHandler.Update, admin...)
+					router.DELETE("/:method", methodHandler.Delete, admin...)
+					router.POST("/:method/deprecate", methodHandler.Deprecate, admin...)
+					router.DELETE("/:method/cache", methodHandler.FlushCache, admin...)
+					router.POST("/:method/cache/invalidate", methodHandler.InvalidateCache, admin...)
+				}
+
+				// User
+				{
+					router := server.Group("/user")
+					router.POST("", userHandler.Register)
+					router.GET("/me", userHandler.Me, userHandler.Authenticate)
+					router.PUT("/:ref/role", userHandler.AssignRole, admin...)
+				}
+
+				// Consent
+				{
+					router := server.Group("/user/me/consent", userHandler.Authenticate)
+					router.GET("", consentHandler.List)
+					router.POST("", consentHandler.Grant)
+					router.DELETE("/:provider/:method", consentHandler.Revoke)
 				}
 
 				// Orquestrate
 				{
-					server.POST("/call", orquestratorHandler.Request)
+					router := server.Group("/call", userHandler.Authenticate)
+					router.POST("", orquestratorHandler.Request)
+					router.GET("/:id", orquestratorHandler.Job)
+					router.DELETE("/:id", orquestratorHandler.Cancel)
 				}
 				return nil
 			},
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/caioeverest/fed-its/service"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+)
+
+const userContextKey = "user"
+
+type User struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service service.UserI
+}
+
+func NewUser(cfg *config.Config, log *logger.Logger, service service.UserI) *User {
+	handler := &User{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+	return handler
+}
+
+// Register godoc
+// @Summary Register a new user
+// @Description Register a new user. The API key is only returned on this response.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param payload body model.User true "User"
+// @Success 201 {object} model.UserCredentials
+// @Failure      400  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user [post]
+func (u *User) Register(pctx echo.Context) (err error) {
+	var (
+		payload model.User
+		result  model.UserCredentials
+		ctx     = pctx.Request().Context()
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		u.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+
+	if result, err = u.service.Register(ctx, payload); err != nil {
+		u.log.Errorf("Error registering user: %v", err)
+		return
+	}
+
+	return pctx.JSON(201, result)
+}
+
+// Me godoc
+// @Summary Get the authenticated user
+// @Description Get the user that owns the API key of the request
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} model.User
+// @Failure      401  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user/me [get]
+func (u *User) Me(pctx echo.Context) (err error) {
+	return pctx.JSON(200, currentUser(pctx))
+}
+
+// AssignRole godoc
+// @Summary Assign a role to a user
+// @Description Change the role of a user. Only federation admins may assign roles.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param ref path string true "User reference"
+// @Param payload body model.RoleAssignment true "Role"
+// @Success 200 {object} model.User
+// @Failure      400  {object}  itserrors.Error
+// @Failure      401  {object}  itserrors.Error
+// @Failure      403  {object}  itserrors.Error
+// @Failure      404  {object}  itserrors.Error
+// @Failure      500  {object}  itserrors.Error
+// @Router /user/{ref}/role [put]
+func (u *User) AssignRole(pctx echo.Context) (err error) {
+	var (
+		payload model.RoleAssignment
+		result  model.User
+		ctx     = pctx.Request().Context()
+		ref     = pctx.Param("ref")
+	)
+
+	if err = pctx.Bind(&payload); err != nil {
+		u.log.Errorf("Error binding payload: %v", err)
+		return
+	}
+
+	if result, err = u.service.AssignRole(ctx, ref, payload.Role); err != nil {
+		u.log.Errorf("Error assigning role: %v", err)
+		return
+	}
+
+	return pctx.JSON(200, result)
+}
+
+// Authenticate is a middleware that resolves the user from the X-API-Key header, or
+// from a bearer Authorization header, and makes it available to the next handlers
+func (u *User) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(pctx echo.Context) (err error) {
+		var (
+			user   model.User
+			ctx    = pctx.Request().Context()
+			apiKey = pctx.Request().Header.Get("X-API-Key")
+		)
+
+		if apiKey == "" {
+			apiKey = strings.TrimPrefix(pctx.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		}
+		if user, err = u.service.Authenticate(ctx, apiKey); err != nil {
+			u.log.Errorf("Error authenticating request: %v", err)
+			return
+		}
+
+		pctx.Set(userContextKey, user)
+		return next(pctx)
+	}
+}
+
+// Require is a middleware, used after Authenticate, that only lets through users with
+// one of the roles
+func (u *User) Require(roles ...model.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(pctx echo.Context) error {
+			user := currentUser(pctx)
+			if !lo.Contains(roles, user.Role) {
+				u.log.Errorf("User %s with role %s is not allowed to %s %s", user.Ref, user.Role, pctx.Request().Method, pctx.Path())
+				return itserrors.ErrForbidden
+			}
+			return next(pctx)
+		}
+	}
+}
+
+// currentUser returns the user resolved by the Authenticate middleware
+func currentUser(pctx echo.Context) model.User {
+	user, _ := pctx.Get(userContextKey).(model.User)
+	return user
+}
@@ -2,27 +2,53 @@ package aes
 
 import (
 	caes "crypto/aes"
-	"crypto/cipher"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 )
 
-// Decrypt takes a secret and a content string and returns the plaintext string.
-// The ciphertext string is hex-decoded before decryption.
-func Decrypt(secret, content string) (result string, err error) {
-	var (
-		ciphertext []byte
-		c          cipher.Block
-		key        = []byte(secret)
-	)
+// Decrypt takes a content produced by Encrypt and returns the plaintext string. Contents
+// in the legacy hex format are decrypted with the default key.
+func (k *Keyring) Decrypt(content string) (result string, err error) {
+	var sealed []byte
 
-	if ciphertext, err = hex.DecodeString(content); err != nil {
+	id, payload, ok := parse(content)
+	if !ok {
+		return k.decryptLegacy(content)
+	}
+	aead, known := k.keys[id]
+	if !known {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	if sealed, err = base64.RawURLEncoding.DecodeString(payload); err != nil {
 		return
 	}
-	if c, err = caes.NewCipher(key); err != nil {
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
 		return
 	}
+	return string(plaintext), nil
+}
+
+// decryptLegacy reads the hex contents written before the versioned format, when a
+// single AES block was encrypted with the default key. Longer contents were truncated
+// by that format and cannot be recovered.
+func (k *Keyring) decryptLegacy(content string) (result string, err error) {
+	var ciphertext []byte
+	if ciphertext, err = hex.DecodeString(content); err != nil {
+		return
+	}
+	if len(ciphertext) != caes.BlockSize {
+		return "", errors.New("legacy ciphertext is not a single block and cannot be recovered")
+	}
 
 	pt := make([]byte, len(ciphertext))
-	c.Decrypt(pt, ciphertext)
+	k.legacy.Decrypt(pt, ciphertext)
 	return string(pt[:]), nil
 }
@@ -1,22 +1,21 @@
 package aes
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"encoding/hex"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
 )
 
-// Encrypt takes a secret and content and encrypts the content with AES
-func Encrypt(secret, content string) (result string, err error) {
+// Encrypt takes a content and encrypts it with AES-GCM under the active key, using a
+// random nonce. The result is formatted as v1:<key id>:<base64 nonce and ciphertext>.
+func (k *Keyring) Encrypt(content string) (result string, err error) {
 	var (
-		c         cipher.Block
-		out       = make([]byte, len(content))
-		plaintext = []byte(content)
-		key       = []byte(secret)
+		aead  = k.keys[k.active]
+		nonce = make([]byte, aead.NonceSize())
 	)
-	if c, err = aes.NewCipher(key); err != nil {
+	if _, err = rand.Read(nonce); err != nil {
 		return
 	}
-	c.Encrypt(out, plaintext)
-	return hex.EncodeToString(out), nil
+	sealed := aead.Seal(nonce, nonce, []byte(content), []byte(k.active))
+	return fmt.Sprintf("%s:%s:%s", version, k.active, base64.RawURLEncoding.EncodeToString(sealed)), nil
 }
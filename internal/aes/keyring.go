@@ -0,0 +1,84 @@
+package aes
+
+import (
+	caes "crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/caioeverest/fed-its/internal/config"
+)
+
+const (
+	// version prefixes every ciphertext produced by the keyring: v1:<key id>:<payload>
+	version = "v1"
+	// defaultKeyID names the key derived from HASH_SECRET
+	defaultKeyID = "default"
+)
+
+var ErrUnknownKey = errors.New("ciphertext was encrypted with an unknown key")
+
+// Keyring encrypts with AES-GCM under the active key and decrypts with any known key,
+// so master keys can be rotated without losing access to older ciphertexts
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+	legacy cipher.Block
+}
+
+// New builds the keyring from HASH_SECRET, registered as the "default" key, and from the
+// ENCRYPTION_KEYS list. ENCRYPTION_KEY_ID selects the key used to encrypt.
+func New(cfg *config.Config) (keyring *Keyring, err error) {
+	var (
+		keys = map[string]string{defaultKeyID: cfg.HashSecret}
+	)
+	keyring = &Keyring{keys: map[string]cipher.AEAD{}, active: cfg.EncryptionKeyID}
+	if keyring.active == "" {
+		keyring.active = defaultKeyID
+	}
+	for id, key := range cfg.EncryptionKeys {
+		keys[id] = key
+	}
+
+	for id, key := range keys {
+		var block cipher.Block
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id %q", id)
+		}
+		if block, err = caes.NewCipher([]byte(key)); err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		if keyring.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+		if id == defaultKeyID {
+			keyring.legacy = block
+		}
+	}
+	if _, ok := keyring.keys[keyring.active]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not configured", keyring.active)
+	}
+	return
+}
+
+// Active returns the ID of the key used to encrypt
+func (k *Keyring) Active() string {
+	return k.active
+}
+
+// NeedsRotation reports if the ciphertext is not under the active key, either because it
+// uses the legacy format or because it was encrypted with another key
+func (k *Keyring) NeedsRotation(content string) bool {
+	id, _, ok := parse(content)
+	return !ok || id != k.active
+}
+
+// parse splits a versioned ciphertext into its key ID and payload
+func parse(content string) (id, payload string, ok bool) {
+	parts := strings.SplitN(content, ":", 3)
+	if len(parts) != 3 || parts[0] != version {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
@@ -0,0 +1,190 @@
+package aes
+
+import (
+	caes "crypto/aes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/caioeverest/fed-its/internal/config"
+)
+
+const (
+	hashSecret = "thisis32bitlongpassphraseimusing"
+	key1       = "anotherkeyof32byteslongforaesgcm"
+	key2       = "yetanotherkeyof24bytes!!"
+)
+
+func newKeyring(t *testing.T, active string, keys map[string]string) *Keyring {
+	t.Helper()
+	keyring, err := New(&config.Config{HashSecret: hashSecret, EncryptionKeys: keys, EncryptionKeyID: active})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return keyring
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  string
+		content string
+	}{
+		{name: "default key", active: "", content: "provider-secret"},
+		{name: "configured key", active: "k1", content: "provider-secret"},
+		{name: "24 bytes key", active: "k2", content: "provider-secret"},
+		{name: "empty content", active: "k1", content: ""},
+		{name: "longer than a block", active: "k1", content: strings.Repeat("0123456789abcdef", 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := newKeyring(t, tt.active, map[string]string{"k1": key1, "k2": key2})
+
+			encrypted, err := keyring.Encrypt(tt.content)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if prefix := "v1:" + keyring.Active() + ":"; !strings.HasPrefix(encrypted, prefix) {
+				t.Errorf("Encrypt() = %q, want prefix %q", encrypted, prefix)
+			}
+			if again, _ := keyring.Encrypt(tt.content); again == encrypted {
+				t.Errorf("Encrypt() gave the same ciphertext twice, the nonce must be random")
+			}
+			decrypted, err := keyring.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if decrypted != tt.content {
+				t.Errorf("Decrypt() = %q, want %q", decrypted, tt.content)
+			}
+			if keyring.NeedsRotation(encrypted) {
+				t.Errorf("NeedsRotation() = true for a ciphertext under the active key")
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := newKeyring(t, "k1", map[string]string{"k1": key1})
+	encrypted, err := old.Encrypt("provider-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	rotated := newKeyring(t, "k2", map[string]string{"k1": key1, "k2": key2})
+	if !rotated.NeedsRotation(encrypted) {
+		t.Errorf("NeedsRotation() = false for a ciphertext under a previous key")
+	}
+	decrypted, err := rotated.Decrypt(encrypted)
+	if err != nil || decrypted != "provider-secret" {
+		t.Fatalf("Decrypt() = %q, %v, want the content under the previous key", decrypted, err)
+	}
+	reencrypted, err := rotated.Encrypt(decrypted)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if rotated.NeedsRotation(reencrypted) {
+		t.Errorf("NeedsRotation() = true after encrypting under the active key")
+	}
+
+	retired := newKeyring(t, "k2", map[string]string{"k2": key2})
+	if _, err = retired.Decrypt(encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() error = %v, want %v once the key is removed", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyringDecryptLegacy(t *testing.T) {
+	block, err := caes.NewCipher([]byte(hashSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := func(content string) string {
+		ciphertext := make([]byte, caes.BlockSize)
+		block.Encrypt(ciphertext, []byte(content))
+		return hex.EncodeToString(ciphertext)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "single block", content: legacy("0123456789abcdef"), want: "0123456789abcdef"},
+		{name: "not hex", content: "not-hex", wantErr: true},
+		{name: "not a single block", content: legacy("0123456789abcdef") + "00", wantErr: true},
+		{name: "unknown version", content: "v2:k1:payload", wantErr: true},
+	}
+	keyring := newKeyring(t, "k1", map[string]string{"k1": key1})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keyring.Decrypt(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+			if !keyring.NeedsRotation(tt.content) {
+				t.Errorf("NeedsRotation() = false for a legacy ciphertext")
+			}
+		})
+	}
+}
+
+func TestKeyringDecryptTampered(t *testing.T) {
+	keyring := newKeyring(t, "k1", map[string]string{"k1": key1, "k2": key2})
+	encrypted, err := keyring.Encrypt("provider-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	payload := strings.TrimPrefix(encrypted, "v1:k1:")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "flipped payload", content: "v1:k1:" + flip(payload)},
+		{name: "other key id", content: "v1:k2:" + payload},
+		{name: "not base64", content: "v1:k1:!!!"},
+		{name: "too short", content: "v1:k1:AAAA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := keyring.Decrypt(tt.content); err == nil {
+				t.Errorf("Decrypt() = %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		active string
+		keys   map[string]string
+	}{
+		{name: "active key missing", active: "k3", keys: map[string]string{"k1": key1}},
+		{name: "invalid key size", active: "k1", keys: map[string]string{"k1": "short"}},
+		{name: "key id with a colon", active: "k1", keys: map[string]string{"k1": key1, "k:2": key2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(&config.Config{HashSecret: hashSecret, EncryptionKeys: tt.keys, EncryptionKeyID: tt.active}); err == nil {
+				t.Errorf("New() error = nil, want an error")
+			}
+		})
+	}
+}
+
+// flip changes a character in the middle of a base64 payload, the last one may only
+// carry padding bits
+func flip(payload string) string {
+	i := len(payload) / 2
+	replacement := byte('A')
+	if payload[i] == 'A' {
+		replacement = 'B'
+	}
+	return payload[:i] + string(replacement) + payload[i+1:]
+}
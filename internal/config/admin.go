@@ -0,0 +1,9 @@
+package config
+
+// Admin is the federation admin created when the application starts, so the first
+// roles can be assigned. It is not created when APIKey is empty.
+type Admin struct {
+	Name   string `env:"NAME" envDefault:"Federation admin"`
+	Email  string `env:"EMAIL" envDefault:"admin@fed-its.local"`
+	APIKey string `env:"API_KEY"`
+}
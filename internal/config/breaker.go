@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type Breaker struct {
+	FailureThreshold int           `env:"FAILURE_THRESHOLD" envDefault:"5"`
+	OpenTimeout      time.Duration `env:"OPEN_TIMEOUT" envDefault:"30s"`
+	HalfOpenRequests int           `env:"HALF_OPEN_REQUESTS" envDefault:"1"`
+	History          int           `env:"HISTORY" envDefault:"50"`
+}
@@ -1,16 +1,32 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v8"
 	"go.uber.org/fx"
 )
 
 type Config struct {
-	Version    string   `env:"VERSION" envDefault:"UNDEFINED"`
-	HashSecret string   `env:"HASH_SECRET,required"`
-	HTTPPort   int      `env:"HTTP_PORT" envDefault:"8000"`
-	Database   Database `envPrefix:"DB_"`
-	Redis      Redis    `envPrefix:"REDIS_"`
+	Version            string            `env:"VERSION" envDefault:"UNDEFINED"`
+	HashSecret         string            `env:"HASH_SECRET,required"`
+	EncryptionKeys     map[string]string `env:"ENCRYPTION_KEYS"`
+	EncryptionKeyID    string            `env:"ENCRYPTION_KEY_ID"`
+	HTTPPort           int               `env:"HTTP_PORT" envDefault:"8000"`
+	ExchangeTTL        time.Duration     `env:"EXCHANGE_TTL" envDefault:"15m"`
+	BroadcastTimeout   time.Duration     `env:"BROADCAST_TIMEOUT" envDefault:"10s"`
+	ProviderTimeout    time.Duration     `env:"PROVIDER_TIMEOUT" envDefault:"30s"`
+	MaxBackoff         time.Duration     `env:"MAX_BACKOFF" envDefault:"30s"`
+	SignatureTolerance time.Duration     `env:"SIGNATURE_TOLERANCE" envDefault:"5m"`
+	SecretGracePeriod  time.Duration     `env:"SECRET_GRACE_PERIOD" envDefault:"24h"`
+	StatsWindow        int               `env:"STATS_WINDOW" envDefault:"100"`
+	HedgeDelay         time.Duration     `env:"HEDGE_DELAY" envDefault:"200ms"`
+	JobTTL             time.Duration     `env:"JOB_TTL" envDefault:"1h"`
+	Database           Database          `envPrefix:"DB_"`
+	Redis              Redis             `envPrefix:"REDIS_"`
+	Health             Health            `envPrefix:"HEALTH_"`
+	Breaker            Breaker           `envPrefix:"BREAKER_"`
+	Admin              Admin             `envPrefix:"ADMIN_"`
 }
 
 var version = "UNDEFINED"
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// Health is how providers are probed. A provider below MinSuccessRate, or slower than
+// MaxLatency, is degraded and below DownSuccessRate it is down.
+type Health struct {
+	Interval        time.Duration `env:"INTERVAL" envDefault:"30s"`
+	Timeout         time.Duration `env:"TIMEOUT" envDefault:"5s"`
+	Concurrency     int           `env:"CONCURRENCY" envDefault:"8"`
+	Window          int           `env:"WINDOW" envDefault:"20"`
+	MinSuccessRate  float64       `env:"MIN_SUCCESS_RATE" envDefault:"0.9"`
+	DownSuccessRate float64       `env:"DOWN_SUCCESS_RATE" envDefault:"0.5"`
+	MaxLatency      time.Duration `env:"MAX_LATENCY" envDefault:"2s"`
+}
@@ -1,16 +1,70 @@
 package itserrors
 
 type Error struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	HTTPStatus int    `json:"http_status"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	HTTPStatus    int    `json:"http_status"`
+	Provider      string `json:"provider,omitempty"`
+	Retryable     bool   `json:"retryable,omitempty"`
+	Details       any    `json:"details,omitempty" swaggertype:"object"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// FieldError describes why a single field of a payload was rejected
+type FieldError struct {
+	Field   string `json:"field" example:"name"`
+	Rule    string `json:"rule" example:"required"`
+	Message string `json:"message" example:"name is required"`
 }
 
 func (e Error) Error() string {
 	return e.Message
 }
 
+// Is matches errors by code, so an error built from one of the errors below with a more
+// specific message or details still matches it
+func (e Error) Is(target error) bool {
+	switch t := target.(type) {
+	case Error:
+		return t.Code == e.Code
+	case *Error:
+		return t != nil && t.Code == e.Code
+	}
+	return false
+}
+
+// WithMessage returns a copy of the error with a more specific message
+func (e Error) WithMessage(message string) Error {
+	e.Message = message
+	return e
+}
+
+// WithDetails returns a copy of the error carrying details about what went wrong
+func (e Error) WithDetails(details any) Error {
+	e.Details = details
+	return e
+}
+
 var (
-	ErrNotFound         = Error{Code: "CLIENT_0001", Message: "Not found", HTTPStatus: 404}
-	ErrInvalidSignature = Error{Code: "CLIENT_0002", Message: "Invalid signature", HTTPStatus: 400}
+	ErrNotFound            = Error{Code: "CLIENT_0001", Message: "Not found", HTTPStatus: 404}
+	ErrInvalidSignature    = Error{Code: "CLIENT_0002", Message: "Invalid signature", HTTPStatus: 400}
+	ErrInvalidParams       = Error{Code: "CLIENT_0003", Message: "Invalid params", HTTPStatus: 400}
+	ErrUnauthorized        = Error{Code: "CLIENT_0004", Message: "Unauthorized", HTTPStatus: 401}
+	ErrNoProvider          = Error{Code: "CLIENT_0005", Message: "No consented provider implements the method", HTTPStatus: 404}
+	ErrValidation          = Error{Code: "CLIENT_0006", Message: "Validation failed", HTTPStatus: 400}
+	ErrInvalidPayload      = Error{Code: "CLIENT_0007", Message: "Invalid payload", HTTPStatus: 400}
+	ErrRouteNotFound       = Error{Code: "CLIENT_0008", Message: "Route not found", HTTPStatus: 404}
+	ErrMethodNotAllowed    = Error{Code: "CLIENT_0009", Message: "Method not allowed", HTTPStatus: 405}
+	ErrConflict            = Error{Code: "CLIENT_0010", Message: "Resource was changed by another request", HTTPStatus: 409}
+	ErrForbidden           = Error{Code: "CLIENT_0011", Message: "Forbidden", HTTPStatus: 403}
+	ErrMethodInUse         = Error{Code: "CLIENT_0012", Message: "Method still has enrolled providers", HTTPStatus: 409}
+	ErrMethodSunset        = Error{Code: "CLIENT_0013", Message: "Method reached its sunset date", HTTPStatus: 410}
+	ErrInvalidResult       = Error{Code: "PROVIDER_0001", Message: "Provider result does not match the method result structure", HTTPStatus: 502}
+	ErrCircuitOpen         = Error{Code: "PROVIDER_0002", Message: "Provider circuit is open", HTTPStatus: 503}
+	ErrProviderFailed      = Error{Code: "PROVIDER_0003", Message: "Provider failed to answer", HTTPStatus: 502}
+	ErrProviderUnreachable = Error{Code: "PROVIDER_0004", Message: "Provider could not be reached", HTTPStatus: 502}
+	ErrProviderTimeout     = Error{Code: "PROVIDER_0005", Message: "Provider took too long to answer", HTTPStatus: 504}
+	ErrNoProviderAnswered  = Error{Code: "PROVIDER_0006", Message: "No provider could handle the request", HTTPStatus: 502}
+	ErrNoConsensus         = Error{Code: "PROVIDER_0007", Message: "Providers did not reach a consensus", HTTPStatus: 502}
+	ErrInternal            = Error{Code: "SERVER_0001", Message: "Internal server error", HTTPStatus: 500}
 )
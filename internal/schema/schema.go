@@ -0,0 +1,279 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Violation describes a value that does not match what was declared
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Violations is the list of every mismatch found on a value
+type Violations []Violation
+
+func (v Violations) Error() string {
+	messages := make([]string, len(v))
+	for i, violation := range v {
+		messages[i] = fmt.Sprintf("%s: %s", violation.Path, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (v *Violations) add(path, format string, args ...any) {
+	*v = append(*v, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v Violations) orNil() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// typeAliases maps every type name accepted in a method declaration to its JSON type
+var typeAliases = map[string]string{
+	"string":  "string",
+	"str":     "string",
+	"int":     "integer",
+	"integer": "integer",
+	"float":   "number",
+	"number":  "number",
+	"bool":    "boolean",
+	"boolean": "boolean",
+	"object":  "object",
+	"map":     "object",
+	"array":   "array",
+	"list":    "array",
+	"null":    "null",
+	"any":     "any",
+}
+
+// CheckParams makes sure every declared param type is known
+func CheckParams(types []string) error {
+	var violations Violations
+	for i, name := range types {
+		if _, ok := typeAliases[normalize(name)]; !ok {
+			violations.add(fmt.Sprintf("params[%d]", i), "unknown type %q", strings.TrimSpace(name))
+		}
+	}
+	return violations.orNil()
+}
+
+// ValidateParams checks that params has one value per declared type and that
+// every value matches the type declared for its position
+func ValidateParams(types []string, params []any) error {
+	var violations Violations
+	if len(types) != len(params) {
+		violations.add("params", "expected %d params, got %d", len(types), len(params))
+		return violations
+	}
+	for i, name := range types {
+		expected, ok := typeAliases[normalize(name)]
+		if !ok {
+			violations.add(fmt.Sprintf("params[%d]", i), "unknown type %q", strings.TrimSpace(name))
+			continue
+		}
+		if !matches(expected, params[i]) {
+			violations.add(fmt.Sprintf("params[%d]", i), "expected %s, got %s", expected, typeOf(params[i]))
+		}
+	}
+	return violations.orNil()
+}
+
+// IsSchema reports if the structure is a JSON Schema rather than a sample document
+func IsSchema(structure map[string]any) bool {
+	if _, ok := structure["$schema"]; ok {
+		return true
+	}
+	_, hasType := schemaTypes(structure["type"])
+	_, hasProperties := structure["properties"].(map[string]any)
+	return hasType || hasProperties
+}
+
+// Validate checks value against structure. The structure may be a JSON Schema
+// (type, properties, required, items and enum are supported) or a sample
+// document, in which case value must carry every key of the sample with a
+// value of the same JSON type.
+func Validate(structure map[string]any, value any) error {
+	var violations Violations
+	if len(structure) == 0 {
+		return nil
+	}
+	if IsSchema(structure) {
+		validateSchema(structure, value, "$", &violations)
+	} else {
+		validateSample(structure, value, "$", &violations)
+	}
+	return violations.orNil()
+}
+
+func validateSchema(schema map[string]any, value any, path string, violations *Violations) {
+	if expected, ok := schemaTypes(schema["type"]); ok {
+		if !lo.ContainsBy(expected, func(t string) bool { return matches(t, value) }) {
+			violations.add(path, "expected %s, got %s", strings.Join(expected, " or "), typeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if !lo.ContainsBy(enum, func(option any) bool { return fmt.Sprint(option) == fmt.Sprint(value) }) {
+			violations.add(path, "value %v is not one of %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, key := range required {
+				if _, ok := v[fmt.Sprint(key)]; !ok {
+					violations.add(fmt.Sprintf("%s.%v", path, key), "is required")
+				}
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]any); ok {
+			for key, property := range properties {
+				field, present := v[key]
+				propertySchema, isSchema := property.(map[string]any)
+				if !present || !isSchema {
+					continue
+				}
+				validateSchema(propertySchema, field, fmt.Sprintf("%s.%s", path, key), violations)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+func validateSample(sample any, value any, path string, violations *Violations) {
+	switch s := sample.(type) {
+	case nil:
+		return
+	case map[string]any:
+		v, ok := value.(map[string]any)
+		if !ok {
+			violations.add(path, "expected object, got %s", typeOf(value))
+			return
+		}
+		for key, field := range s {
+			fieldPath := fmt.Sprintf("%s.%s", path, key)
+			if _, present := v[key]; !present {
+				violations.add(fieldPath, "is required")
+				continue
+			}
+			validateSample(field, v[key], fieldPath, violations)
+		}
+	case []any:
+		v, ok := value.([]any)
+		if !ok {
+			violations.add(path, "expected array, got %s", typeOf(value))
+			return
+		}
+		if len(s) == 0 {
+			return
+		}
+		for i, item := range v {
+			validateSample(s[0], item, fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+	default:
+		if expected := typeOf(sample); !matches(expected, value) {
+			violations.add(path, "expected %s, got %s", expected, typeOf(value))
+		}
+	}
+}
+
+func schemaTypes(t any) ([]string, bool) {
+	switch v := t.(type) {
+	case string:
+		return []string{v}, true
+	case []any:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			types = append(types, fmt.Sprint(item))
+		}
+		return types, len(types) > 0
+	}
+	return nil, false
+}
+
+func matches(expected string, value any) bool {
+	switch expected {
+	case "any":
+		return true
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == expected
+	}
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// HasField reports if the structure declares the field at the dotted path, like
+// "closure.status". Both JSON Schemas and sample documents are supported.
+func HasField(structure map[string]any, path string) bool {
+	var current any = structure
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := current.(map[string]any)
+		if !ok {
+			return false
+		}
+		if IsSchema(fields) {
+			if fields, ok = fields["properties"].(map[string]any); !ok {
+				return false
+			}
+		}
+		if current, ok = fields[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Field returns the value at the dotted path of a decoded JSON value
+func Field(value any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,216 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// decode reads a JSON document the way the handlers do, so numbers are float64
+func decode(t *testing.T, document string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("invalid test document %s - %v", document, err)
+	}
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		structure string
+		value     string
+		want      []string
+	}{
+		{name: "empty structure", structure: `{}`, value: `"anything"`},
+		{name: "schema type", structure: `{"type":"string"}`, value: `"closed"`},
+		{name: "schema wrong type", structure: `{"type":"string"}`, value: `1`, want: []string{"$"}},
+		{name: "schema integer", structure: `{"type":"integer"}`, value: `2`},
+		{name: "schema integer with a fraction", structure: `{"type":"integer"}`, value: `2.5`, want: []string{"$"}},
+		{name: "schema number", structure: `{"type":"number"}`, value: `2.5`},
+		{name: "schema several types", structure: `{"type":["string","null"]}`, value: `null`},
+		{name: "schema several types mismatch", structure: `{"type":["string","null"]}`, value: `true`, want: []string{"$"}},
+		{name: "schema enum", structure: `{"type":"string","enum":["open","closed"]}`, value: `"closed"`},
+		{name: "schema enum mismatch", structure: `{"type":"string","enum":["open","closed"]}`, value: `"unknown"`, want: []string{"$"}},
+		{
+			name:      "schema properties and required",
+			structure: `{"type":"object","required":["id","status"],"properties":{"id":{"type":"string"},"status":{"type":"string"}}}`,
+			value:     `{"id":"BR-101","status":"closed","extra":1}`,
+		},
+		{
+			name:      "schema missing required",
+			structure: `{"type":"object","required":["id","status"],"properties":{"id":{"type":"string"}}}`,
+			value:     `{"id":"BR-101"}`,
+			want:      []string{"$.status"},
+		},
+		{
+			name:      "schema optional property absent",
+			structure: `{"properties":{"id":{"type":"string"},"reason":{"type":"string"}}}`,
+			value:     `{"id":"BR-101"}`,
+		},
+		{
+			name:      "schema property of the wrong type",
+			structure: `{"properties":{"id":{"type":"string"}}}`,
+			value:     `{"id":101}`,
+			want:      []string{"$.id"},
+		},
+		{
+			name:      "schema items",
+			structure: `{"type":"array","items":{"type":"object","required":["km"],"properties":{"km":{"type":"number"}}}}`,
+			value:     `[{"km":1},{"km":2.5}]`,
+		},
+		{
+			name:      "schema items rejected",
+			structure: `{"type":"array","items":{"type":"object","required":["km"],"properties":{"km":{"type":"number"}}}}`,
+			value:     `[{"km":1},{},{"km":"far"}]`,
+			want:      []string{"$[1].km", "$[2].km"},
+		},
+		{
+			name:      "schema nested object",
+			structure: `{"type":"object","properties":{"route":{"type":"object","required":["steps"],"properties":{"steps":{"type":"array","items":{"type":"string"}}}}}}`,
+			value:     `{"route":{"steps":["a","b"]}}`,
+		},
+		{
+			name:      "schema nested object rejected",
+			structure: `{"type":"object","properties":{"route":{"type":"object","required":["steps"],"properties":{"steps":{"type":"array","items":{"type":"string"}}}}}}`,
+			value:     `{"route":{"steps":["a",2]}}`,
+			want:      []string{"$.route.steps[1]"},
+		},
+		{name: "schema declared with $schema", structure: `{"$schema":"http://json-schema.org/draft-07/schema#","required":["id"]}`, value: `{}`, want: []string{"$.id"}},
+		{name: "sample", structure: `{"id":"BR-101","closed":true,"km":12}`, value: `{"id":"BR-116","closed":false,"km":3.5,"extra":null}`},
+		{name: "sample missing key", structure: `{"id":"BR-101","closed":true}`, value: `{"id":"BR-116"}`, want: []string{"$.closed"}},
+		{name: "sample wrong type", structure: `{"id":"BR-101","closed":true}`, value: `{"id":116,"closed":"yes"}`, want: []string{"$.id", "$.closed"}},
+		{name: "sample not an object", structure: `{"id":"BR-101"}`, value: `["BR-101"]`, want: []string{"$"}},
+		{name: "sample null accepts anything", structure: `{"reason":null}`, value: `{"reason":{"text":"works"}}`},
+		{name: "sample nested", structure: `{"route":{"from":"A","steps":[{"km":1}]}}`, value: `{"route":{"from":"B","steps":[{"km":2},{"km":3}]}}`},
+		{name: "sample nested rejected", structure: `{"route":{"from":"A","steps":[{"km":1}]}}`, value: `{"route":{"steps":[{"km":2},{"km":"far"}]}}`, want: []string{"$.route.from", "$.route.steps[1].km"}},
+		{name: "sample empty list accepts any item", structure: `{"steps":[]}`, value: `{"steps":[1,"two",{}]}`},
+		{name: "sample list not a list", structure: `{"steps":[]}`, value: `{"steps":{}}`, want: []string{"$.steps"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			structure := decode(t, tt.structure).(map[string]any)
+			err := Validate(structure, decode(t, tt.value))
+			if got := violationPaths(err); fmt.Sprint(got) != fmt.Sprint(sorted(tt.want)) {
+				t.Errorf("Validate() error = %v, want violations on %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []string
+		want  []string
+	}{
+		{name: "no params", types: nil},
+		{name: "known types and aliases", types: []string{"string", "str", "int", "integer", "float", "number", "bool", "boolean", "object", "map", "array", "list", "null", "any"}},
+		{name: "case and spaces", types: []string{" String ", "INT"}},
+		{name: "unknown types", types: []string{"string", "date", "int", "uuid"}, want: []string{"params[1]", "params[3]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckParams(tt.types)
+			if got := violationPaths(err); fmt.Sprint(got) != fmt.Sprint(sorted(tt.want)) {
+				t.Errorf("CheckParams() error = %v, want violations on %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		types  []string
+		params string
+		want   []string
+	}{
+		{name: "matching", types: []string{"string", "int", "bool", "any"}, params: `["BR-101", 12, true, {"a":1}]`},
+		{name: "wrong count", types: []string{"string", "int"}, params: `["BR-101"]`, want: []string{"params"}},
+		{name: "wrong types", types: []string{"string", "int", "float"}, params: `[101, 1.5, "x"]`, want: []string{"params[0]", "params[1]", "params[2]"}},
+		{name: "unknown type", types: []string{"date"}, params: `["2023-06-01"]`, want: []string{"params[0]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParams(tt.types, decode(t, tt.params).([]any))
+			if got := violationPaths(err); fmt.Sprint(got) != fmt.Sprint(sorted(tt.want)) {
+				t.Errorf("ValidateParams() error = %v, want violations on %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasField(t *testing.T) {
+	var (
+		schema = `{"type":"object","properties":{"closure":{"type":"object","properties":{"status":{"type":"string"}}},"id":{"type":"string"}}}`
+		sample = `{"closure":{"status":"closed"},"id":"BR-101","steps":[{"km":1}]}`
+	)
+	tests := []struct {
+		name      string
+		structure string
+		path      string
+		want      bool
+	}{
+		{name: "schema top level", structure: schema, path: "id", want: true},
+		{name: "schema nested", structure: schema, path: "closure.status", want: true},
+		{name: "schema missing", structure: schema, path: "closure.reason", want: false},
+		{name: "schema below a leaf", structure: schema, path: "id.value", want: false},
+		{name: "sample top level", structure: sample, path: "id", want: true},
+		{name: "sample nested", structure: sample, path: "closure.status", want: true},
+		{name: "sample missing", structure: sample, path: "reason", want: false},
+		{name: "sample through a list", structure: sample, path: "steps.km", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasField(decode(t, tt.structure).(map[string]any), tt.path); got != tt.want {
+				t.Errorf("HasField() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	value := map[string]any{"closure": map[string]any{"status": "closed"}, "id": "BR-101"}
+	tests := []struct {
+		path      string
+		want      any
+		wantFound bool
+	}{
+		{path: "id", want: "BR-101", wantFound: true},
+		{path: "closure.status", want: "closed", wantFound: true},
+		{path: "closure.reason", wantFound: false},
+		{path: "id.value", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, found := Field(value, tt.path)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("Field() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+// violationPaths lists, sorted, the paths of the violations err carries
+func violationPaths(err error) []string {
+	var (
+		violations Violations
+		result     []string
+	)
+	if errors.As(err, &violations) {
+		for _, violation := range violations {
+			result = append(result, violation.Path)
+		}
+	}
+	return sorted(result)
+}
+
+func sorted(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
@@ -1,11 +1,25 @@
 package validate
 
-import "github.com/go-playground/validator/v10"
+import (
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// camelCase accepts names made of letters and digits that start with a letter, like
+// methodName or MethodName
+var camelCase = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
 
 type Validate struct {
 	*validator.Validate
 }
 
 func New() *Validate {
-	return &Validate{validator.New()}
+	v := validator.New()
+	if err := v.RegisterValidation("camelCase", func(fl validator.FieldLevel) bool {
+		return camelCase.MatchString(fl.Field().String())
+	}); err != nil {
+		panic(err)
+	}
+	return &Validate{v}
 }
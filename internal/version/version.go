@@ -0,0 +1,138 @@
+// Package version compares the semantic versions of methods and matches them against
+// the version constraints sent by callers.
+//
+// A constraint is a list of terms, separated by spaces or commas, that must all match:
+//
+//	1.2.3          exactly 1.2.3, "=1.2.3" also works
+//	1, 1.2         any version of major 1, or of minor 1.2
+//	^1.2.3         1.2.3 or newer, with the same major
+//	^0.2.3         0.2.3 or newer, with the same minor, like npm the leftmost
+//	               non-zero part is kept and ^0.0.3 only matches 0.0.3
+//	~1.2.3         1.2.3 or newer, with the same minor
+//	>=1.2.3, <2    comparisons with >, >=, < and <=
+//
+// Pre-release versions only match a constraint that names a pre-release.
+package version
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Compare returns -1, 0 or 1 when a is older, equal or newer than b
+func Compare(a, b string) int {
+	return semver.Compare(canonical(a), canonical(b))
+}
+
+// Latest returns the newest of the versions that match the constraint
+func Latest(versions []string, constraint Constraint) (latest string, found bool) {
+	for _, version := range versions {
+		if !constraint.Match(version) {
+			continue
+		}
+		if !found || Compare(version, latest) > 0 {
+			latest, found = version, true
+		}
+	}
+	return
+}
+
+// Constraint is a parsed version constraint, the empty constraint matches every stable version
+type Constraint struct {
+	terms      []term
+	prerelease bool
+}
+
+type term struct {
+	operator string
+	version  string
+}
+
+// ParseConstraint reads a version constraint
+func ParseConstraint(constraint string) (parsed Constraint, err error) {
+	for _, raw := range strings.FieldsFunc(constraint, func(r rune) bool { return r == ' ' || r == ',' }) {
+		var t term
+		for _, operator := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(raw, operator) {
+				t.operator, raw = operator, strings.TrimSpace(raw[len(operator):])
+				break
+			}
+		}
+		if t.version = canonical(raw); !semver.IsValid(t.version) {
+			return Constraint{}, fmt.Errorf("invalid version %q in constraint %q", raw, constraint)
+		}
+		if semver.Prerelease(t.version) != "" {
+			parsed.prerelease = true
+		}
+		parsed.terms = append(parsed.terms, t)
+	}
+	return
+}
+
+// Match tells whether the version satisfies every term of the constraint
+func (c Constraint) Match(version string) bool {
+	var v = canonical(version)
+	if !semver.IsValid(v) || (!c.prerelease && semver.Prerelease(v) != "") {
+		return false
+	}
+	for _, t := range c.terms {
+		if !t.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) match(v string) bool {
+	switch t.operator {
+	case ">=":
+		return semver.Compare(v, t.version) >= 0
+	case ">":
+		return semver.Compare(v, t.version) > 0
+	case "<=":
+		return semver.Compare(v, t.version) <= 0
+	case "<":
+		return semver.Compare(v, t.version) < 0
+	case "^":
+		return semver.Compare(v, t.version) >= 0 && caret(v, t.version)
+	case "~":
+		return semver.Compare(v, t.version) >= 0 && semver.MajorMinor(v) == semver.MajorMinor(t.version)
+	}
+	// A partial version, like 1 or 1.2, matches every version under it
+	switch strings.Count(t.version, ".") {
+	case 0:
+		return semver.Major(v) == t.version
+	case 1:
+		return semver.MajorMinor(v) == t.version
+	}
+	return semver.Compare(v, t.version) == 0
+}
+
+// caret tells whether v is in the caret range of base, which keeps the leftmost non-zero
+// part of base, or its last part when base is partial and zero up to it
+func caret(v, base string) bool {
+	switch {
+	case semver.Major(base) != "v0" || parts(base) == 1:
+		return semver.Major(v) == semver.Major(base)
+	case semver.MajorMinor(base) != "v0.0" || parts(base) == 2:
+		return semver.MajorMinor(v) == semver.MajorMinor(base)
+	}
+	return core(v) == core(base)
+}
+
+// parts counts the parts of a version, 1 for "v1", 2 for "v1.2" and 3 for "v1.2.3"
+func parts(version string) int {
+	return strings.Count(strings.SplitN(strings.SplitN(version, "-", 2)[0], "+", 2)[0], ".") + 1
+}
+
+// core is the version without its pre-release and build, like v1.2.3
+func core(version string) string {
+	return strings.TrimSuffix(semver.Canonical(version), semver.Prerelease(version))
+}
+
+// canonical adds the v prefix golang.org/x/mod/semver expects
+func canonical(version string) string {
+	return "v" + strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
@@ -0,0 +1,116 @@
+package version
+
+import "testing"
+
+func TestConstraintMatch(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{constraint: "", version: "1.2.3", want: true},
+		{constraint: "", version: "1.2.3-rc.1", want: false},
+		{constraint: "1.2.3", version: "1.2.3", want: true},
+		{constraint: "=1.2.3", version: "1.2.4", want: false},
+		{constraint: "v1.2.3", version: "1.2.3", want: true},
+		{constraint: "1", version: "1.9.0", want: true},
+		{constraint: "1", version: "2.0.0", want: false},
+		{constraint: "1.2", version: "1.2.9", want: true},
+		{constraint: "1.2", version: "1.3.0", want: false},
+		{constraint: "^1.2.3", version: "1.2.3", want: true},
+		{constraint: "^1.2.3", version: "1.9.0", want: true},
+		{constraint: "^1.2.3", version: "1.2.2", want: false},
+		{constraint: "^1.2.3", version: "2.0.0", want: false},
+		{constraint: "^0.2.3", version: "0.2.3", want: true},
+		{constraint: "^0.2.3", version: "0.2.9", want: true},
+		{constraint: "^0.2.3", version: "0.2.2", want: false},
+		{constraint: "^0.2.3", version: "0.3.0", want: false},
+		{constraint: "^0.2", version: "0.2.0", want: true},
+		{constraint: "^0.2", version: "0.3.0", want: false},
+		{constraint: "^0.0.3", version: "0.0.3", want: true},
+		{constraint: "^0.0.3", version: "0.0.4", want: false},
+		{constraint: "^0.0.3", version: "0.1.0", want: false},
+		{constraint: "^0.0", version: "0.0.7", want: true},
+		{constraint: "^0.0", version: "0.1.0", want: false},
+		{constraint: "^0", version: "0.9.0", want: true},
+		{constraint: "^0", version: "1.0.0", want: false},
+		{constraint: "~1.2.3", version: "1.2.9", want: true},
+		{constraint: "~1.2.3", version: "1.3.0", want: false},
+		{constraint: ">=1.2.3, <2", version: "1.5.0", want: true},
+		{constraint: ">=1.2.3 <2", version: "2.0.0", want: false},
+		{constraint: ">1.2.3", version: "1.2.3", want: false},
+		{constraint: "<=1.2.3", version: "1.2.3", want: true},
+		{constraint: "^1.2.3-rc.1", version: "1.2.3-rc.2", want: true},
+		{constraint: "^1.2.3-rc.1", version: "1.2.3", want: true},
+		{constraint: "^1.2.3-rc.1", version: "1.2.3-beta", want: false},
+		{constraint: "1", version: "not-a-version", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			if got := constraint.Match(tt.version); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{"latest", "^", ">=1.x", "1.2.3.4"} {
+		t.Run(constraint, func(t *testing.T) {
+			if _, err := ParseConstraint(constraint); err == nil {
+				t.Errorf("ParseConstraint() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	versions := []string{"1.0.0", "1.10.0", "1.2.0", "2.0.0-rc.1", "0.2.5", "0.3.0"}
+	tests := []struct {
+		constraint string
+		want       string
+		wantFound  bool
+	}{
+		{constraint: "", want: "1.10.0", wantFound: true},
+		{constraint: "^1.0.0", want: "1.10.0", wantFound: true},
+		{constraint: "~1.2.0", want: "1.2.0", wantFound: true},
+		{constraint: "^0.2.0", want: "0.2.5", wantFound: true},
+		{constraint: ">=2.0.0-rc.1", want: "2.0.0-rc.1", wantFound: true},
+		{constraint: "3", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			got, found := Latest(versions, constraint)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("Latest() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "v2.0.0", b: "2.0.0", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
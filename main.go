@@ -7,6 +7,7 @@ import (
 	"github.com/caioeverest/fed-its/adapter/http"
 	"github.com/caioeverest/fed-its/adapter/redis"
 	"github.com/caioeverest/fed-its/handler"
+	"github.com/caioeverest/fed-its/internal/aes"
 	"github.com/caioeverest/fed-its/internal/config"
 	"github.com/caioeverest/fed-its/internal/logger"
 	"github.com/caioeverest/fed-its/internal/validate"
@@ -30,8 +31,8 @@ func main() {
 		handler.Invoke(),
 		service.Services(),
 		fx.Provide(http.New, database.New, redis.New),
-		fx.Provide(validate.New, logger.New, config.New),
-		fx.Invoke(model.Migrate, func(*http.Server) {}),
+		fx.Provide(validate.New, logger.New, config.New, aes.New),
+		fx.Invoke(model.Migrate, service.RotateSecrets, service.BootstrapAdmin, func(*http.Server) {}),
 	)
 	defer close(app)
 	app.Run()
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerState is the circuit breaker of a provider, shared by every instance through redis
+type BreakerState struct {
+	Provider    string              `json:"provider" example:"provider-slug"`
+	State       string              `json:"state" example:"closed"`
+	Failures    int                 `json:"failures" example:"0"`
+	Trials      int                 `json:"trials" example:"0"`
+	Since       time.Time           `json:"since"`
+	Transitions []BreakerTransition `json:"transitions,omitempty"`
+}
+
+// BreakerTransition records a change of state of a circuit breaker
+type BreakerTransition struct {
+	From   string    `json:"from" example:"closed"`
+	To     string    `json:"to" example:"open"`
+	Reason string    `json:"reason" example:"5 consecutive failures"`
+	At     time.Time `json:"at"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	BackoffConstant    = "constant"
+	BackoffExponential = "exponential"
+)
+
+// CallPolicy is how long the orquestrator waits for a provider answer and how it
+// retries a call that failed for a retryable reason
+type CallPolicy struct {
+	TimeoutMs     int    `gorm:"not null;default:0" validate:"min=0" json:"timeout_ms" example:"500"`
+	Retries       int    `gorm:"not null;default:0" validate:"min=0,max=10" json:"retries" example:"2"`
+	BackoffMs     int    `gorm:"not null;default:0" validate:"min=0,max=60000" json:"backoff_ms" example:"100"`
+	BackoffPolicy string `gorm:"not null;default:constant" validate:"omitempty,oneof=constant exponential" json:"backoff_policy" example:"exponential"`
+}
+
+// CallPolicyOverride replaces the fields of a method call policy for a single provider
+type CallPolicyOverride struct {
+	TimeoutMs     *int    `validate:"omitempty,min=0" json:"timeout_ms,omitempty" example:"10000"`
+	Retries       *int    `validate:"omitempty,min=0,max=10" json:"retries,omitempty" example:"1"`
+	BackoffMs     *int    `validate:"omitempty,min=0,max=60000" json:"backoff_ms,omitempty" example:"250"`
+	BackoffPolicy *string `validate:"omitempty,oneof=constant exponential" json:"backoff_policy,omitempty" example:"constant"`
+}
+
+// Override returns the policy with every field set on the override replaced
+func (p CallPolicy) Override(o CallPolicyOverride) CallPolicy {
+	if o.TimeoutMs != nil {
+		p.TimeoutMs = *o.TimeoutMs
+	}
+	if o.Retries != nil {
+		p.Retries = *o.Retries
+	}
+	if o.BackoffMs != nil {
+		p.BackoffMs = *o.BackoffMs
+	}
+	if o.BackoffPolicy != nil {
+		p.BackoffPolicy = *o.BackoffPolicy
+	}
+	return p
+}
+
+// Timeout returns how long to wait for each attempt, or fallback when the policy has none
+func (p CallPolicy) Timeout(fallback time.Duration) time.Duration {
+	if p.TimeoutMs <= 0 {
+		return fallback
+	}
+	return time.Duration(p.TimeoutMs) * time.Millisecond
+}
+
+// Backoff returns how long to wait before the given retry, starting from zero, capped at
+// max. A max of zero or less leaves the backoff uncapped.
+func (p CallPolicy) Backoff(retry int, max time.Duration) time.Duration {
+	if max <= 0 {
+		max = math.MaxInt64
+	}
+	backoff := time.Duration(p.BackoffMs) * time.Millisecond
+	if backoff >= max {
+		return max
+	}
+	if p.BackoffPolicy == BackoffExponential {
+		for ; retry > 0; retry-- {
+			//Doubling past max/2 would exceed the cap, or overflow
+			if backoff > max/2 {
+				return max
+			}
+			backoff *= 2
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCallPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CallPolicy
+		retry  int
+		max    time.Duration
+		want   time.Duration
+	}{
+		{name: "constant", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffConstant}, retry: 3, max: time.Minute, want: 100 * time.Millisecond},
+		{name: "no policy is constant", policy: CallPolicy{BackoffMs: 100}, retry: 3, max: time.Minute, want: 100 * time.Millisecond},
+		{name: "constant above max", policy: CallPolicy{BackoffMs: 5000, BackoffPolicy: BackoffConstant}, retry: 0, max: time.Second, want: time.Second},
+		{name: "exponential first retry", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffExponential}, retry: 0, max: time.Minute, want: 100 * time.Millisecond},
+		{name: "exponential third retry", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffExponential}, retry: 2, max: time.Minute, want: 400 * time.Millisecond},
+		{name: "exponential reaching max", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffExponential}, retry: 4, max: time.Second, want: time.Second},
+		{name: "exponential capped at max", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffExponential}, retry: 10, max: time.Second, want: time.Second},
+		{name: "exponential without backoff", policy: CallPolicy{BackoffPolicy: BackoffExponential}, retry: 5, max: time.Second, want: 0},
+		{name: "uncapped", policy: CallPolicy{BackoffMs: 100, BackoffPolicy: BackoffExponential}, retry: 10, max: 0, want: 102400 * time.Millisecond},
+		{name: "uncapped does not overflow", policy: CallPolicy{BackoffMs: 60000, BackoffPolicy: BackoffExponential}, retry: 100, max: 0, want: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.retry, tt.max); got != tt.want {
+				t.Errorf("Backoff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// Fields is a list of dotted paths into the result of a method, like "closure.status"
+type Fields []string
+
+func (f *Fields) GormDataType() string {
+	return "VARCHAR(255)"
+}
+
+func (f *Fields) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	case nil:
+	default:
+		return errors.New("src value cannot cast to []byte")
+	}
+	*f = nil
+	if raw != "" {
+		*f = strings.Split(raw, ",")
+	}
+	return nil
+}
+
+func (f Fields) Value() (driver.Value, error) {
+	return strings.Join(f, ","), nil
+}
+
+// ConsensusReport tells how the providers of a consensus call agreed. The agreement is the
+// share of the called providers that gave the majority answer.
+type ConsensusReport struct {
+	Agreement  float64  `json:"agreement" example:"0.67"`
+	Quorum     int      `json:"quorum" example:"2"`
+	Agreeing   []string `json:"agreeing" example:"Provider A,Provider B"`
+	Dissenting []string `json:"dissenting,omitempty" example:"Provider C"`
+	Failed     []string `json:"failed,omitempty" example:"Provider D"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Consent is the grant a user gives to a provider to receive the user's calls of a method
+type Consent struct {
+	ID         uint       `gorm:"primarykey" json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_consent" json:"-"`
+	ProviderID uint       `gorm:"not null;uniqueIndex:idx_consent" json:"-"`
+	MethodID   uint       `gorm:"not null;uniqueIndex:idx_consent" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Provider   string     `gorm:"->;-:migration" json:"provider" example:"provider-slug"`
+	Method     string     `gorm:"->;-:migration" json:"method" example:"MethodName"`
+}
+
+// ConsentGrant is the payload a user sends to allow a provider to receive its calls of a method
+type ConsentGrant struct {
+	Provider  string     `json:"provider" validate:"required" example:"provider-slug"`
+	Method    string     `json:"method" validate:"required" example:"MethodName"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2030-01-01T00:00:00Z"`
+}
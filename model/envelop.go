@@ -1,7 +1,27 @@
 package model
 
+import "github.com/caioeverest/fed-its/internal/itserrors"
+
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
+
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
 type Envelope struct {
-	Provider string `json:"provider"`
-	Version  string `json:"version"`
-	Result   any    `json:"result"`
+	Provider string           `json:"provider"`
+	Version  string           `json:"version"`
+	Result   any              `json:"result"`
+	Status   string           `json:"status,omitempty" example:"ok"`
+	Error    *itserrors.Error `json:"error,omitempty"`
+	Results  []Envelope       `json:"results,omitempty"`
+	Cache    string           `json:"cache,omitempty" example:"miss"`
+	Exchange *ExchangeStep    `json:"exchange,omitempty"`
+	// Consensus is set on the answer of a consensus method
+	Consensus *ConsensusReport `json:"consensus,omitempty"`
+	// Deprecation is set when the called method is deprecated
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
 }
@@ -0,0 +1,23 @@
+package model
+
+// ExchangeStep identifies the round of a multi-step conversation with a provider
+type ExchangeStep struct {
+	Token string `json:"token" example:"3f1c0c3e5d6b4a3f9e1b2c7d8a9f0e1d"`
+	Step  int    `json:"step" example:"1"`
+	Done  bool   `json:"done"`
+}
+
+// ExchangeState is what the orquestrator keeps between the rounds of an exchange
+type ExchangeState struct {
+	ExchangeStep
+	Method   string `json:"method"`
+	Version  string `json:"version"`
+	Provider string `json:"provider"`
+	UserRef  string `json:"user_ref"`
+}
+
+// ExchangeReply is the body a provider answers with on each round of an exchange
+type ExchangeReply struct {
+	Done   bool `json:"done"`
+	Result any  `json:"result"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+const (
+	HealthUnknown  = "unknown"
+	HealthUp       = "up"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthSample is the outcome of a single probe of a provider
+type HealthSample struct {
+	Success bool          `json:"success"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+	At      time.Time     `json:"at"`
+}
+
+// ProviderHealth summarizes the latest samples of a provider
+type ProviderHealth struct {
+	Provider    string    `json:"provider" example:"provider-slug"`
+	Status      string    `json:"status" example:"up"`
+	SuccessRate float64   `json:"success_rate" example:"0.95"`
+	AvgLatency  int64     `json:"avg_latency_ms" example:"120"`
+	Samples     int       `json:"samples" example:"20"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastCheck   time.Time `json:"last_check"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+)
+
+// JobStatus is the stage of an asynchronous call
+type JobStatus string
+
+const (
+	JobPending  JobStatus = "pending"
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobFailed   JobStatus = "failed"
+	JobCanceled JobStatus = "canceled"
+)
+
+// Finished reports if the job will no longer change
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+// Job is an asynchronous call, kept in redis until the job TTL expires. The result is set
+// once the job is done and the error once it failed.
+type Job struct {
+	ID        string           `json:"id" example:"9b2f4c1d7e3a4b6c8d0e1f2a3b4c5d6e"`
+	Status    JobStatus        `json:"status" enums:"pending,running,done,failed,canceled" example:"running"`
+	Method    string           `json:"method,omitempty" example:"MethodName"`
+	Result    *Envelope        `json:"result,omitempty"`
+	Error     *itserrors.Error `json:"error,omitempty"`
+	CreatedAt time.Time        `json:"created_at" example:"2023-06-01T12:00:00Z"`
+	UpdatedAt time.Time        `json:"updated_at" example:"2023-06-01T12:00:05Z"`
+}
+
+// JobState is what the orquestrator keeps of a job in redis
+type JobState struct {
+	Job
+	UserRef string `json:"user_ref"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MergeStrategy is how the values of a field answered by the providers of a broadcast
+// are combined into the merged result
+type MergeStrategy string
+
+const (
+	// MergeConcat concatenates the lists of every provider
+	MergeConcat MergeStrategy = "concat"
+	// MergeDedupe concatenates the lists of every provider and keeps the first item of each key
+	MergeDedupe MergeStrategy = "dedupe"
+	// MergePreferNonEmpty keeps the first value that is not null, an empty string, list or object
+	MergePreferNonEmpty MergeStrategy = "prefer_non_empty"
+	// MergeMin keeps the lowest number
+	MergeMin MergeStrategy = "min"
+	// MergeMax keeps the highest number
+	MergeMax MergeStrategy = "max"
+	// MergeAvg averages the numbers
+	MergeAvg MergeStrategy = "avg"
+)
+
+// MergeRule combines the values of a field, a dotted path like "route.steps", with a
+// strategy. The dedupe strategy compares the items of the lists by the key path, or by
+// the whole item when no key is set.
+type MergeRule struct {
+	Field    string        `json:"field" validate:"required" example:"route.steps"`
+	Strategy MergeStrategy `json:"strategy" validate:"required,oneof=concat dedupe prefer_non_empty min max avg" enums:"concat,dedupe,prefer_non_empty,min,max,avg" example:"dedupe"`
+	Key      string        `json:"key,omitempty" example:"id"`
+}
+
+// MergeRules are the rules a broadcast method merges the results of its providers with.
+// Fields without a rule keep the first non-empty value, objects are merged key by key.
+type MergeRules []MergeRule
+
+func (r *MergeRules) GormDataType() string { return "JSONB" }
+
+func (r *MergeRules) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	}
+	return errors.New("src value cannot cast to []byte")
+}
+
+func (r MergeRules) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(r)
+	return string(bytes), err
+}
+
+func (r *MergeRules) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql", "sqlite":
+		return "JSON"
+	case "postgres":
+		return "JSONB"
+	}
+	return ""
+}
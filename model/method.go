@@ -2,28 +2,154 @@ package model
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/samber/lo"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
 
 type Method struct {
 	gorm.Model      `json:"-"`
-	Name            string          `gorm:"not null;index" json:"name" validate:"camelCase,required" example:"MethodName"`
+	Name            string          `gorm:"not null;index;uniqueIndex:idx_method_version,where:deleted_at IS NULL" json:"name" validate:"camelCase,required" example:"MethodName"`
+	Version         string          `gorm:"not null;default:1.0.0;uniqueIndex:idx_method_version,where:deleted_at IS NULL" json:"version" validate:"required,semver" example:"1.0.0"`
 	Params          Params          `gorm:"not null" validate:"required" json:"params" example:"string, string, int"`
 	Description     string          `gorm:"not null" validate:"required" json:"description" example:"This method does an operation"`
 	ResultStructure ResultStructure `gorm:"not null" validate:"required" json:"result_structure" example:"{ \"key\": \"value\" }"`
-	Kind            MethodKind      `gorm:"not null;default:concurrent" validate:"required" json:"kind" example:"concurrent"`
+	Kind            MethodKind      `gorm:"not null;default:concurrent" validate:"required,oneof=broadcast concurrent indepotent exchange hedged consensus" json:"kind" enums:"broadcast,concurrent,indepotent,exchange,hedged,consensus" example:"concurrent"`
+	CacheTTL        int             `gorm:"not null;default:0" validate:"min=0" json:"cache_ttl" example:"300"`
+	CachePerUser    bool            `gorm:"not null;default:false" json:"cache_per_user" example:"false"`
+	CallPolicy
+	Selection          SelectionStrategy `gorm:"not null;default:ordered" validate:"omitempty,oneof=ordered round_robin weighted lowest_latency cheapest priority random" json:"selection" enums:"ordered,round_robin,weighted,lowest_latency,cheapest,priority,random" example:"lowest_latency"`
+	SelectionLimit     int               `gorm:"not null;default:0" validate:"min=0" json:"selection_limit" example:"0"`
+	HedgeDelayMs       int               `gorm:"not null;default:0" validate:"min=0" json:"hedge_delay_ms" example:"150"`
+	ConsensusKeys      Fields            `gorm:"not null;default:''" json:"consensus_keys,omitempty" swaggertype:"array,string" example:"closed,reason"`
+	ConsensusQuorum    int               `gorm:"not null;default:0" validate:"omitempty,min=2" json:"consensus_quorum" example:"2"`
+	MergeRules         MergeRules        `validate:"omitempty,dive" json:"merge_rules,omitempty"`
+	DeprecatedAt       *time.Time        `json:"deprecated_at,omitempty" example:"2023-06-01T00:00:00Z"`
+	SunsetAt           *time.Time        `json:"sunset_at,omitempty" example:"2023-09-01T00:00:00Z"`
+	DeprecationMessage string            `json:"deprecation_message,omitempty" example:"Use OtherMethod instead"`
+}
+
+// DefaultVersion is the version of a method created without one
+const DefaultVersion = "1.0.0"
+
+// MethodUpdate replaces the fields of a method that are set, the call policy fields are
+// flat like on the method and replaced one by one. The name and the version of a method
+// never change, a new version is created instead.
+type MethodUpdate struct {
+	Params          *Params         `json:"params,omitempty" swaggertype:"array,string" example:"string,string,int"`
+	Description     *string         `json:"description,omitempty" example:"This method does an operation"`
+	ResultStructure ResultStructure `json:"result_structure,omitempty"`
+	Kind            *MethodKind     `json:"kind,omitempty" enums:"broadcast,concurrent,indepotent,exchange,hedged,consensus" example:"concurrent"`
+	CacheTTL        *int            `json:"cache_ttl,omitempty" example:"300"`
+	CachePerUser    *bool           `json:"cache_per_user,omitempty" example:"false"`
+	CallPolicyOverride
+	Selection       *SelectionStrategy `json:"selection,omitempty" enums:"ordered,round_robin,weighted,lowest_latency,cheapest,priority,random" example:"lowest_latency"`
+	SelectionLimit  *int               `json:"selection_limit,omitempty" example:"2"`
+	HedgeDelayMs    *int               `json:"hedge_delay_ms,omitempty" example:"150"`
+	ConsensusKeys   *Fields            `json:"consensus_keys,omitempty" swaggertype:"array,string" example:"closed,reason"`
+	ConsensusQuorum *int               `json:"consensus_quorum,omitempty" example:"2"`
+	MergeRules      MergeRules         `json:"merge_rules,omitempty"`
+}
+
+// Apply returns the method with every field set on the update replaced
+func (u MethodUpdate) Apply(m Method) Method {
+	if u.Params != nil {
+		m.Params = *u.Params
+	}
+	if u.Description != nil {
+		m.Description = *u.Description
+	}
+	if u.ResultStructure != nil {
+		m.ResultStructure = u.ResultStructure
+	}
+	if u.Kind != nil {
+		m.Kind = *u.Kind
+	}
+	if u.CacheTTL != nil {
+		m.CacheTTL = *u.CacheTTL
+	}
+	if u.CachePerUser != nil {
+		m.CachePerUser = *u.CachePerUser
+	}
+	m.CallPolicy = m.CallPolicy.Override(u.CallPolicyOverride)
+	if u.Selection != nil {
+		m.Selection = *u.Selection
+	}
+	if u.SelectionLimit != nil {
+		m.SelectionLimit = *u.SelectionLimit
+	}
+	if u.HedgeDelayMs != nil {
+		m.HedgeDelayMs = *u.HedgeDelayMs
+	}
+	if u.ConsensusKeys != nil {
+		m.ConsensusKeys = *u.ConsensusKeys
+	}
+	if u.ConsensusQuorum != nil {
+		m.ConsensusQuorum = *u.ConsensusQuorum
+	}
+	if u.MergeRules != nil {
+		m.MergeRules = u.MergeRules
+	}
+	return m
+}
+
+// MethodDeprecation is the payload an admin sends to deprecate a method. Calls are
+// refused once the sunset date is reached.
+type MethodDeprecation struct {
+	SunsetAt *time.Time `json:"sunset_at,omitempty" example:"2023-09-01T00:00:00Z"`
+	Message  string     `json:"message,omitempty" example:"Use OtherMethod instead"`
+}
+
+// Deprecation is reported on the envelope of every call of a deprecated method
+type Deprecation struct {
+	DeprecatedAt time.Time  `json:"deprecated_at" example:"2023-06-01T00:00:00Z"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty" example:"2023-09-01T00:00:00Z"`
+	Message      string     `json:"message,omitempty" example:"Use OtherMethod instead"`
+}
+
+// Deprecation returns the deprecation of the method, or nil when it is not deprecated
+func (m Method) Deprecation() *Deprecation {
+	if m.DeprecatedAt == nil {
+		return nil
+	}
+	return &Deprecation{DeprecatedAt: *m.DeprecatedAt, SunsetAt: m.SunsetAt, Message: m.DeprecationMessage}
+}
+
+// Sunset tells whether the method reached its sunset date and must no longer be called
+func (m Method) Sunset(now time.Time) bool {
+	return m.DeprecatedAt != nil && m.SunsetAt != nil && !now.Before(*m.SunsetAt)
 }
 
 type ResultStructure map[string]any
 
 func (r *ResultStructure) GormDataType() string { return "JSONB" }
 
+func (r *ResultStructure) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	}
+	return errors.New("src value cannot cast to []byte")
+}
+
+func (r ResultStructure) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(r)
+	return string(bytes), err
+}
+
 func (r *ResultStructure) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 	switch db.Dialector.Name() {
 	case "mysql", "sqlite":
@@ -41,11 +167,14 @@ func (p *Params) GormDataType() string {
 }
 
 func (p *Params) Scan(src any) error {
-	bytes, ok := src.([]byte)
-	if !ok {
+	switch v := src.(type) {
+	case []byte:
+		*p = strings.Split(string(v), ",")
+	case string:
+		*p = strings.Split(v, ",")
+	default:
 		return errors.New("src value cannot cast to []byte")
 	}
-	*p = strings.Split(string(bytes), ",")
 	return nil
 }
 
@@ -56,57 +185,46 @@ func (p Params) Value() (driver.Value, error) {
 	return strings.Join(p, ","), nil
 }
 
-type MethodKind byte
+// MethodKind is how the orquestrator spreads a call among the providers of a method.
+// It is stored in the method_kind Postgres enum type, created by the migrator.
+type MethodKind string
 
 const (
-	Broadcast MethodKind = iota
-	Concurrent
-	Indepotent
-	Exchange
+	Broadcast  MethodKind = "broadcast"
+	Concurrent MethodKind = "concurrent"
+	Indepotent MethodKind = "indepotent"
+	Exchange   MethodKind = "exchange"
+	// Hedged calls the first provider and only calls the next one when no answer arrived
+	// within the hedge delay, or when the previous one failed
+	Hedged MethodKind = "hedged"
+	// Consensus calls every provider and answers what most of them agree on, comparing
+	// the consensus keys of their results
+	Consensus MethodKind = "consensus"
 )
 
-var (
-	methodKindToString = map[MethodKind]string{
-		Broadcast:  "broadcast",
-		Concurrent: "concurrent",
-		Indepotent: "indepotent",
-		Exchange:   "exchange",
-	}
-	stringToMethodKind = map[string]MethodKind{
-		"broadcast":  Broadcast,
-		"concurrent": Concurrent,
-		"indepotent": Indepotent,
-		"exchange":   Exchange,
-	}
-)
+// MethodKinds lists every kind, in the order they were added to the enum type
+var MethodKinds = []MethodKind{Broadcast, Concurrent, Indepotent, Exchange, Hedged, Consensus}
 
-func (m *MethodKind) GormDataType() string {
-	keys := lo.Map(lo.Keys(stringToMethodKind), func(k string, _ int) string { return fmt.Sprintf("'%s'", k) })
-	return fmt.Sprintf("enum(%s)", strings.Join(keys, ","))
+func (m MethodKind) GormDataType() string {
+	return "method_kind"
 }
 
 func (m *MethodKind) Scan(src any) error {
-	bytes, ok := src.([]byte)
-	if !ok {
-		return errors.New("src value cannot cast to []byte")
+	switch v := src.(type) {
+	case []byte:
+		*m = MethodKind(v)
+	case string:
+		*m = MethodKind(v)
+	default:
+		return fmt.Errorf("cannot scan %T into MethodKind", src)
 	}
-	*m = stringToMethodKind[string(bytes)]
 	return nil
 }
 
 func (m MethodKind) Value() (driver.Value, error) {
-	return methodKindToString[m], nil
+	return string(m), nil
 }
 
 func (m MethodKind) String() string {
-	return methodKindToString[m]
-}
-
-func (m MethodKind) MarshalJSON() ([]byte, error) {
-	return []byte(m.String()), nil
-}
-
-func (m *MethodKind) UnmarshalJSON(b []byte) error {
-	*m = stringToMethodKind[string(b)]
-	return nil
+	return string(m)
 }
@@ -1,8 +1,24 @@
 package model
 
+import "time"
+
 type MethodProvider struct {
-	MethodID   uint `gorm:"not null;index" json:"method_id"`
-	Method     Method
-	ProviderID uint `gorm:"not null;index" json:"provider_id"`
-	Provider   Provider
+	MethodID   uint               `gorm:"not null;uniqueIndex:idx_method_provider" json:"method_id"`
+	Method     Method             `json:"method"`
+	ProviderID uint               `gorm:"not null;uniqueIndex:idx_method_provider" json:"provider_id"`
+	Provider   Provider           `json:"-"`
+	Overrides  CallPolicyOverride `gorm:"embedded;embeddedPrefix:override_" json:"overrides"`
+	Weight     int                `gorm:"not null;default:1" json:"weight" example:"1"`
+	Priority   int                `gorm:"not null;default:0" json:"priority" example:"0"`
+	Cost       float64            `gorm:"not null;default:0" json:"cost" example:"0.01"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// Enrollment is the payload a provider sends to enroll in a method: the call policy
+// overrides and what the selection strategies of the method use to order providers
+type Enrollment struct {
+	CallPolicyOverride
+	Weight   int     `validate:"min=0" json:"weight,omitempty" example:"1"`
+	Priority int     `json:"priority,omitempty" example:"0"`
+	Cost     float64 `validate:"min=0" json:"cost,omitempty" example:"0.01"`
 }
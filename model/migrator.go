@@ -2,8 +2,11 @@ package model
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/samber/lo"
 	"go.uber.org/fx"
 )
 
@@ -14,14 +17,58 @@ func Migrate(lc fx.Lifecycle, db *database.Database) {
 				if err = db.AutoMigrate(&Provider{}); err != nil {
 					return err
 				}
+				if err = createEnum(db, MethodKind("").GormDataType(), lo.Map(MethodKinds, func(kind MethodKind, _ int) string { return string(kind) })); err != nil {
+					return err
+				}
 				if err = db.AutoMigrate(&Method{}); err != nil {
 					return err
 				}
 				if err = db.AutoMigrate(&MethodProvider{}); err != nil {
 					return err
 				}
+				if err = db.AutoMigrate(&User{}); err != nil {
+					return err
+				}
+				if err = db.AutoMigrate(&Consent{}); err != nil {
+					return err
+				}
 				return
 			},
 		},
 	)
 }
+
+// createEnum creates a Postgres enum type, or adds to it the values it is missing. Type
+// statements take no bind parameters, the name and the values are quoted instead.
+func createEnum(db *database.Database, name string, values []string) (err error) {
+	var exists bool
+	if err = db.Raw("SELECT EXISTS (SELECT 1 FROM pg_type WHERE typname = ?)", name).Scan(&exists).Error; err != nil {
+		return
+	}
+	if !exists {
+		quoted := lo.Map(values, func(value string, _ int) string { return quoteLiteral(value) })
+		return db.Exec(fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", quoteIdentifier(name), strings.Join(quoted, ", "))).Error
+	}
+	for _, value := range values {
+		if err = db.Exec(fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", quoteIdentifier(name), quoteLiteral(value))).Error; err != nil {
+			return
+		}
+	}
+	return
+}
+
+// quoteIdentifier quotes a Postgres identifier, doubling the quotes it holds
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes a Postgres string literal, doubling the quotes it holds. Literals
+// holding backslashes are escape strings, so they mean the same whatever the value of
+// standard_conforming_strings.
+func quoteLiteral(literal string) string {
+	literal = strings.ReplaceAll(literal, `'`, `''`)
+	if strings.Contains(literal, `\`) {
+		return `E'` + strings.ReplaceAll(literal, `\`, `\\`) + `'`
+	}
+	return `'` + literal + `'`
+}
@@ -2,16 +2,24 @@ package model
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"time"
 
 	"github.com/caioeverest/fed-its/internal/aes"
+	"github.com/caioeverest/fed-its/signature"
 	"github.com/imroc/req/v3"
 	"gorm.io/gorm"
 )
 
+// ProviderStatus tells whether a provider may receive user traffic
+type ProviderStatus string
+
+const (
+	ProviderPending  ProviderStatus = "pending"
+	ProviderApproved ProviderStatus = "approved"
+	ProviderRejected ProviderStatus = "rejected"
+)
+
 type Provider struct {
 	gorm.Model `json:"-"`
 	Name       string `gorm:"not null" validate:"required" json:"name" example:"Example LTDA"`
@@ -19,39 +27,89 @@ type Provider struct {
 	Slug       string `gorm:"not null;uniqueIndex" validate:"required,lowercase" json:"slug" example:"provider-slug"`
 	Webhook    string `gorm:"not null" validate:"required,url" json:"webhook" example:"https://provider.com/webhook"`
 	Secret     string `gorm:"not null" validate:"required" json:"secret"`
+	HealthURL  string `validate:"omitempty,url" json:"health_url,omitempty" example:"https://provider.com/health"`
+	// Providers registered before the approval workflow existed stay approved, new ones
+	// are created pending
+	Status ProviderStatus `gorm:"not null;default:approved;index" json:"status" example:"pending"`
+	// PreviousSecret stays valid, alongside Secret, until PreviousSecretExpiresAt
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"-"`
+}
+
+// SecretRotation is the new secret of a provider, shown only once, and until when the
+// previous secret is still accepted
+type SecretRotation struct {
+	Secret             string    `json:"secret" example:"5f2b6c0e9d..."`
+	PreviousValidUntil time.Time `json:"previous_valid_until" example:"2023-06-16T12:00:00Z"`
 }
 
 type ReqPayload struct {
-	UserRef string `json:"user_ref" example:"user-ref"`
-	Method  string `json:"method" example:"method-name"`
-	Params  []any  `json:"params" example:"[\"param1\", \"param2\"]"`
+	UserRef  string        `json:"user_ref" example:"user-ref"`
+	Method   string        `json:"method" example:"method-name"`
+	Version  string        `json:"version" example:"1.0.0"`
+	Params   []any         `json:"params" example:"[\"param1\", \"param2\"]"`
+	Exchange *ExchangeStep `json:"exchange,omitempty"`
 }
 
-func (p Provider) CallProviderMethod(ctx context.Context, hashSecret, userRef, methodName string, params []any) (result *req.Response, err error) {
-	var (
-		secret    string
-		bytes     []byte
-		signature string
-		payload   = ReqPayload{
-			UserRef: userRef,
-			Method:  methodName,
-			Params:  params,
+func (p Provider) CallProviderMethod(ctx context.Context, keyring *aes.Keyring, userRef, methodName, version string, params []any) (result *req.Response, err error) {
+	return p.send(ctx, keyring, ReqPayload{
+		UserRef: userRef,
+		Method:  methodName,
+		Version: version,
+		Params:  params,
+	})
+}
+
+// CallProviderExchange calls a method of the provider as one round of an exchange
+func (p Provider) CallProviderExchange(ctx context.Context, keyring *aes.Keyring, userRef, methodName, version string, params []any, exchange ExchangeStep) (result *req.Response, err error) {
+	return p.send(ctx, keyring, ReqPayload{
+		UserRef:  userRef,
+		Method:   methodName,
+		Version:  version,
+		Params:   params,
+		Exchange: &exchange,
+	})
+}
+
+// Secrets decrypts the secrets the provider signs with: the current one and, during the
+// grace period of a rotation, the previous one
+func (p Provider) Secrets(keyring *aes.Keyring) (secrets []string, err error) {
+	var secret string
+	if secret, err = keyring.Decrypt(p.Secret); err != nil {
+		return
+	}
+	secrets = append(secrets, secret)
+
+	if p.PreviousSecret != "" && p.PreviousSecretExpiresAt != nil && time.Now().Before(*p.PreviousSecretExpiresAt) {
+		if secret, err = keyring.Decrypt(p.PreviousSecret); err != nil {
+			return
 		}
+		secrets = append(secrets, secret)
+	}
+	return
+}
+
+// send posts the payload to the provider webhook, signed with every valid provider secret
+func (p Provider) send(ctx context.Context, keyring *aes.Keyring, payload ReqPayload) (result *req.Response, err error) {
+	var (
+		secrets []string
+		bytes   []byte
+		id      string
 	)
 
 	if bytes, err = json.Marshal(payload); err != nil {
 		return
 	}
-	if secret, err = aes.Decrypt(hashSecret, p.Secret); err != nil {
+	if secrets, err = p.Secrets(keyring); err != nil {
+		return
+	}
+	if id, err = signature.NewID(); err != nil {
 		return
 	}
-	hash := hmac.New(sha256.New, []byte(secret))
-	hash.Write(bytes)
-	signature = hex.EncodeToString(hash.Sum(nil))
 
 	return req.R().
 		SetContext(ctx).
 		SetBodyJsonBytes(bytes).
-		SetHeader("X-Signature", signature).
+		SetHeader(signature.Header, signature.Sign(time.Now(), id, payload.Method, bytes, secrets...)).
 		Post(p.Webhook)
 }
@@ -0,0 +1,9 @@
+package model
+
+// ProviderError is the body a provider may answer with a non-2xx status to explain
+// why it failed, either as {"error": {...}} or as {"error": "message"}
+type ProviderError struct {
+	Code      string `json:"code" example:"QUOTA_EXCEEDED"`
+	Message   string `json:"message" example:"Daily quota exceeded"`
+	Retryable *bool  `json:"retryable,omitempty" example:"false"`
+}
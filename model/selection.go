@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// SelectionStrategy is how the orquestrator orders the providers of a method before
+// calling them
+type SelectionStrategy string
+
+const (
+	// SelectionOrdered keeps the providers in enrollment order, healthy ones first
+	SelectionOrdered SelectionStrategy = "ordered"
+	// SelectionRoundRobin starts each call on the provider after the one the previous call started on
+	SelectionRoundRobin SelectionStrategy = "round_robin"
+	// SelectionWeighted shuffles the providers, the higher the enrollment weight the likelier to come first
+	SelectionWeighted SelectionStrategy = "weighted"
+	// SelectionLowestLatency prefers the providers that answered the method faster and more reliably
+	SelectionLowestLatency SelectionStrategy = "lowest_latency"
+	// SelectionCheapest prefers the providers with the lowest enrollment cost
+	SelectionCheapest SelectionStrategy = "cheapest"
+	// SelectionPriority follows the enrollment priority, lowest first
+	SelectionPriority SelectionStrategy = "priority"
+	// SelectionRandom shuffles the providers, combined with a selection limit it calls N random providers
+	SelectionRandom SelectionStrategy = "random"
+)
+
+// CallSample is the outcome of a single call of a method to a provider
+type CallSample struct {
+	Success bool          `json:"success"`
+	Latency time.Duration `json:"latency"`
+	At      time.Time     `json:"at"`
+}
+
+// CallStats summarizes the latest calls of a version of a method to a provider
+type CallStats struct {
+	Provider    string  `json:"provider" example:"provider-slug"`
+	Method      string  `json:"method" example:"MethodName"`
+	Version     string  `json:"version" example:"1.0.0"`
+	Calls       int     `json:"calls" example:"100"`
+	SuccessRate float64 `json:"success_rate" example:"0.98"`
+	AvgLatency  int64   `json:"avg_latency_ms" example:"85"`
+	P95Latency  int64   `json:"p95_latency_ms" example:"140"`
+}
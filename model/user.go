@@ -0,0 +1,36 @@
+package model
+
+import "gorm.io/gorm"
+
+// Role is what a user is allowed to manage in the federation
+type Role string
+
+const (
+	// RoleFederationAdmin defines methods, approves providers and assigns roles
+	RoleFederationAdmin Role = "federation_admin"
+	// RoleProviderOperator registers providers, which wait for an admin approval
+	RoleProviderOperator Role = "provider_operator"
+	// RoleConsumer calls methods of the providers it consented to
+	RoleConsumer Role = "consumer"
+)
+
+type User struct {
+	gorm.Model `json:"-"`
+	Ref        string `gorm:"not null;uniqueIndex" json:"ref" example:"5b0f1a2e9c4d4e7f8a1b2c3d4e5f6a7b"`
+	Name       string `gorm:"not null" validate:"required" json:"name" example:"John Doe"`
+	Email      string `gorm:"not null;uniqueIndex" validate:"required,email" json:"email" example:"john@email.com"`
+	APIKeyHash string `gorm:"not null;uniqueIndex" json:"-"`
+	Role       Role   `gorm:"not null;default:consumer" json:"role" example:"consumer"`
+}
+
+// RoleAssignment is the payload an admin sends to change the role of a user
+type RoleAssignment struct {
+	Role Role `json:"role" validate:"required,oneof=federation_admin provider_operator consumer" example:"provider_operator"`
+}
+
+// UserCredentials is returned once, when the user registers, and carries the API key
+// that must be sent on the X-API-Key header of authenticated requests
+type UserCredentials struct {
+	User
+	APIKey string `json:"api_key" example:"fedits_4f2c..."`
+}
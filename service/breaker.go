@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// transitionScript changes the breaker state only if it is still the expected one, so
+// when several instances race for the same transition only one of them performs it
+var transitionScript = goredis.NewScript(`
+local state = redis.call('HGET', KEYS[1], 'state') or 'closed'
+if state ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'state', ARGV[2], 'failures', 0, 'trials', 0, 'since', ARGV[3])
+return 1
+`)
+
+type BreakerI interface {
+	Allow(ctx context.Context, slug string) error
+	Success(ctx context.Context, slug string)
+	Failure(ctx context.Context, slug string)
+	Get(ctx context.Context, slug string) (model.BreakerState, error)
+}
+
+type Breaker struct {
+	cfg   *config.Config
+	log   *logger.Logger
+	db    *database.Database
+	redis *redis.Client
+}
+
+func NewBreaker(cfg *config.Config, log *logger.Logger, db *database.Database, redis *redis.Client) BreakerI {
+	return &Breaker{cfg, log, db, redis}
+}
+
+// Allow returns ErrCircuitOpen when the provider must not be called. An open circuit
+// becomes half-open once the open timeout elapses, letting a few trial calls through.
+// If the breaker state cannot be read the call is allowed.
+func (b *Breaker) Allow(ctx context.Context, slug string) (err error) {
+	var state model.BreakerState
+	if state, err = b.load(ctx, slug); err != nil {
+		b.log.Errorf("Error loading circuit breaker of provider %s - %+v", slug, err)
+		return nil
+	}
+
+	switch state.State {
+	case model.BreakerOpen:
+		if time.Since(state.Since) < b.cfg.Breaker.OpenTimeout {
+			return itserrors.ErrCircuitOpen
+		}
+		b.transition(ctx, slug, model.BreakerOpen, model.BreakerHalfOpen, "open timeout elapsed")
+	case model.BreakerHalfOpen:
+		// Trials that never reported back would keep the circuit half-open forever
+		if state.Trials >= b.cfg.Breaker.HalfOpenRequests && time.Since(state.Since) >= b.cfg.Breaker.OpenTimeout {
+			b.transition(ctx, slug, model.BreakerHalfOpen, model.BreakerHalfOpen, "half-open trials timed out")
+		}
+	default:
+		return nil
+	}
+
+	trials, err := b.redis.HIncrBy(ctx, breakerKey(slug), "trials", 1).Result()
+	if err != nil {
+		b.log.Errorf("Error counting circuit breaker trials of provider %s - %+v", slug, err)
+		return nil
+	}
+	if int(trials) > b.cfg.Breaker.HalfOpenRequests {
+		return itserrors.ErrCircuitOpen
+	}
+	return nil
+}
+
+// Success closes a half-open circuit and clears the failures of a closed one
+func (b *Breaker) Success(ctx context.Context, slug string) {
+	state, err := b.load(ctx, slug)
+	if err != nil {
+		b.log.Errorf("Error loading circuit breaker of provider %s - %+v", slug, err)
+		return
+	}
+
+	switch state.State {
+	case model.BreakerHalfOpen:
+		b.transition(ctx, slug, model.BreakerHalfOpen, model.BreakerClosed, "trial call succeeded")
+	case model.BreakerClosed:
+		if state.Failures > 0 {
+			if err = b.redis.HSet(ctx, breakerKey(slug), "failures", 0).Err(); err != nil {
+				b.log.Errorf("Error resetting circuit breaker failures of provider %s - %+v", slug, err)
+			}
+		}
+	}
+}
+
+// Failure opens a half-open circuit, or a closed one that reached the failure threshold
+func (b *Breaker) Failure(ctx context.Context, slug string) {
+	state, err := b.load(ctx, slug)
+	if err != nil {
+		b.log.Errorf("Error loading circuit breaker of provider %s - %+v", slug, err)
+		return
+	}
+
+	switch state.State {
+	case model.BreakerHalfOpen:
+		b.transition(ctx, slug, model.BreakerHalfOpen, model.BreakerOpen, "trial call failed")
+	case model.BreakerClosed:
+		failures, err := b.redis.HIncrBy(ctx, breakerKey(slug), "failures", 1).Result()
+		if err != nil {
+			b.log.Errorf("Error counting circuit breaker failures of provider %s - %+v", slug, err)
+			return
+		}
+		if int(failures) >= b.cfg.Breaker.FailureThreshold {
+			b.transition(ctx, slug, model.BreakerClosed, model.BreakerOpen, fmt.Sprintf("%d consecutive failures", failures))
+		}
+	}
+}
+
+// Get godoc
+// @Summary Get provider circuit breaker
+// @Description Get the circuit breaker state of a provider and its latest transitions
+func (b *Breaker) Get(ctx context.Context, slug string) (state model.BreakerState, err error) {
+	var (
+		provider model.Provider
+		raw      []string
+	)
+	b.log.Infof("Get circuit breaker of provider %s", slug)
+	if err = b.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		b.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+	if state, err = b.load(ctx, slug); err != nil {
+		b.log.Errorf("Error loading circuit breaker - %+v", err)
+		return
+	}
+	if raw, err = b.redis.LRange(ctx, breakerTransitionsKey(slug), 0, -1).Result(); err != nil {
+		b.log.Errorf("Error loading circuit breaker transitions - %+v", err)
+		return
+	}
+	for _, item := range raw {
+		var transition model.BreakerTransition
+		if err = json.Unmarshal([]byte(item), &transition); err != nil {
+			return
+		}
+		state.Transitions = append(state.Transitions, transition)
+	}
+	return
+}
+
+func (b *Breaker) load(ctx context.Context, slug string) (state model.BreakerState, err error) {
+	var fields map[string]string
+	state = model.BreakerState{Provider: slug, State: model.BreakerClosed}
+	if fields, err = b.redis.HGetAll(ctx, breakerKey(slug)).Result(); err != nil || len(fields) == 0 {
+		return
+	}
+
+	if fields["state"] != "" {
+		state.State = fields["state"]
+	}
+	state.Failures, _ = strconv.Atoi(fields["failures"])
+	state.Trials, _ = strconv.Atoi(fields["trials"])
+	if since, err := strconv.ParseInt(fields["since"], 10, 64); err == nil {
+		state.Since = time.Unix(0, since)
+	}
+	return
+}
+
+// transition moves the breaker from one state to another and records it, unless another
+// call already moved it
+func (b *Breaker) transition(ctx context.Context, slug, from, to, reason string) {
+	var (
+		now   = time.Now()
+		bytes []byte
+	)
+	done, err := transitionScript.Run(ctx, b.redis, []string{breakerKey(slug)}, from, to, now.UnixNano()).Int()
+	if err != nil {
+		b.log.Errorf("Error changing circuit breaker of provider %s - %+v", slug, err)
+		return
+	}
+	if done == 0 {
+		return
+	}
+
+	b.log.Warnf("Circuit breaker of provider %s went from %s to %s: %s", slug, from, to, reason)
+	if bytes, err = json.Marshal(model.BreakerTransition{From: from, To: to, Reason: reason, At: now}); err != nil {
+		return
+	}
+	pipe := b.redis.TxPipeline()
+	pipe.LPush(ctx, breakerTransitionsKey(slug), bytes)
+	pipe.LTrim(ctx, breakerTransitionsKey(slug), 0, int64(b.cfg.Breaker.History-1))
+	if _, err = pipe.Exec(ctx); err != nil {
+		b.log.Errorf("Error recording circuit breaker transition of provider %s - %+v", slug, err)
+	}
+}
+
+func breakerKey(slug string) string {
+	return fmt.Sprintf("breaker:%s", slug)
+}
+
+func breakerTransitionsKey(slug string) string {
+	return fmt.Sprintf("breaker:%s:transitions", slug)
+}
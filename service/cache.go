@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/model"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// withCache answers the call from the cache when the method has a cache TTL and the same
+// params were already answered, otherwise it runs handle and caches its result
+func (o *Orquestrator) withCache(ctx context.Context, method model.Method, userRef string, params []any, handle func() (model.Envelope, error)) (result model.Envelope, err error) {
+	var (
+		key   string
+		bytes []byte
+	)
+	if method.CacheTTL <= 0 {
+		return handle()
+	}
+
+	if key, err = cacheKey(method, userRef, params); err != nil {
+		o.log.Errorf("Error building cache key for method %s: %+v", method.Name, err)
+		return
+	}
+	if bytes, err = o.redis.Get(ctx, key).Bytes(); err == nil {
+		if err = json.Unmarshal(bytes, &result); err == nil {
+			o.log.Infof("Cache hit for method %s", method.Name)
+			result.Cache = model.CacheHit
+			return
+		}
+	}
+	if !errors.Is(err, goredis.Nil) {
+		o.log.Errorf("Error reading cache for method %s: %+v", method.Name, err)
+	}
+
+	o.log.Infof("Cache miss for method %s", method.Name)
+	if result, err = handle(); err != nil {
+		return
+	}
+	result.Cache = model.CacheMiss
+	if bytes, err = json.Marshal(result); err == nil {
+		err = o.redis.Set(ctx, key, bytes, time.Duration(method.CacheTTL)*time.Second).Err()
+	}
+	if err != nil {
+		o.log.Errorf("Error writing cache for method %s: %+v", method.Name, err)
+	}
+	return result, nil
+}
+
+// cacheKey identifies a call by method, method version, canonicalized params and, when the method
+// caches per user, the user reference
+func cacheKey(method model.Method, userRef string, params []any) (string, error) {
+	var (
+		bytes []byte
+		err   error
+		owner = "*"
+	)
+	// encoding/json sorts map keys, so equal params always produce the same bytes
+	if bytes, err = json.Marshal(params); err != nil {
+		return "", err
+	}
+	if method.CachePerUser {
+		owner = userRef
+	}
+	sum := sha256.Sum256(bytes)
+	return fmt.Sprintf("cache:%s:%s:%s:%s", method.Name, method.Version, owner, hex.EncodeToString(sum[:])), nil
+}
+
+// invalidateCache removes every cached call of the method whose key matches the pattern
+func invalidateCache(ctx context.Context, client *redis.Client, pattern string) (removed int64, err error) {
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		var n int64
+		if n, err = client.Del(ctx, iter.Val()).Result(); err != nil {
+			return
+		}
+		removed += n
+	}
+	return removed, iter.Err()
+}
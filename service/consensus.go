@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/schema"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/samber/lo"
+)
+
+// handleConsensus calls every provider like a broadcast and groups the answers by the
+// values of the consensus keys. The largest group wins when it reaches the quorum and
+// is not tied. When there are fewer providers than the quorum none is called, a single
+// answer is never a consensus.
+func (o *Orquestrator) handleConsensus(ctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) (result model.Envelope, err error) {
+	var (
+		groups    = map[string][]model.Envelope{}
+		order     []string
+		consensus = model.ConsensusReport{Quorum: quorum(method, len(listOfProviders))}
+	)
+	if len(listOfProviders) < consensus.Quorum {
+		o.log.Errorf("Method %s needs %d providers to agree but only %d can be called", method.Name, consensus.Quorum, len(listOfProviders))
+		return result, itserrors.ErrNoConsensus.WithMessage(fmt.Sprintf("Quorum of %d providers cannot be reached with %d providers", consensus.Quorum, len(listOfProviders))).WithDetails(consensus)
+	}
+	results := o.callEvery(ctx, method, listOfProviders, userRef, params)
+
+	//Group answers
+	for _, envelope := range results {
+		if envelope.Status != model.StatusOK {
+			consensus.Failed = append(consensus.Failed, envelope.Provider)
+			continue
+		}
+		answer, err := consensusAnswer(method, envelope.Result)
+		if err != nil {
+			o.log.Errorf("Error comparing the answer of provider %s - %+v", envelope.Provider, err)
+			consensus.Failed = append(consensus.Failed, envelope.Provider)
+			continue
+		}
+		if _, found := groups[answer]; !found {
+			order = append(order, answer)
+		}
+		groups[answer] = append(groups[answer], envelope)
+	}
+
+	//Pick the majority
+	var majority string
+	tied := false
+	for _, answer := range order {
+		switch {
+		case majority == "" || len(groups[answer]) > len(groups[majority]):
+			majority, tied = answer, false
+		case len(groups[answer]) == len(groups[majority]):
+			tied = true
+		}
+	}
+	for _, answer := range order {
+		for _, envelope := range groups[answer] {
+			if answer == majority {
+				consensus.Agreeing = append(consensus.Agreeing, envelope.Provider)
+			} else {
+				consensus.Dissenting = append(consensus.Dissenting, envelope.Provider)
+			}
+		}
+	}
+	consensus.Agreement = float64(len(consensus.Agreeing)) / float64(len(listOfProviders))
+	o.log.Infof("Got %d distinct answers from %d providers, %d agree", len(groups), len(results), len(consensus.Agreeing))
+
+	if majority == "" || tied || len(consensus.Agreeing) < consensus.Quorum {
+		return result, itserrors.ErrNoConsensus.WithDetails(consensus)
+	}
+	agreed := groups[majority][0]
+	return model.Envelope{
+		Provider:  agreed.Provider,
+		Result:    agreed.Result,
+		Status:    model.StatusOK,
+		Results:   results,
+		Consensus: &consensus,
+	}, nil
+}
+
+// minQuorum is the least providers that must agree, one provider is not trusted alone
+const minQuorum = 2
+
+// quorum is how many providers must agree, the quorum of the method when it is set or
+// else more than half of the providers, and never less than two
+func quorum(method model.Method, providers int) int {
+	required := providers/2 + 1
+	if method.ConsensusQuorum > 0 {
+		required = method.ConsensusQuorum
+	}
+	return lo.Max([]int{required, minQuorum})
+}
+
+// consensusAnswer reduces a result to the values of the consensus keys, encoded so equal
+// answers give the same string. Without consensus keys the whole result is compared.
+func consensusAnswer(method model.Method, result any) (string, error) {
+	if len(method.ConsensusKeys) == 0 {
+		bytes, err := json.Marshal(result)
+		return string(bytes), err
+	}
+	values := make(map[string]any, len(method.ConsensusKeys))
+	for _, key := range method.ConsensusKeys {
+		values[key], _ = schema.Field(result, key)
+	}
+	bytes, err := json.Marshal(values)
+	return string(bytes), err
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/caioeverest/fed-its/model"
+)
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		name      string
+		quorum    int
+		providers int
+		want      int
+	}{
+		{name: "single provider", providers: 1, want: 2},
+		{name: "two providers", providers: 2, want: 2},
+		{name: "three providers", providers: 3, want: 2},
+		{name: "four providers", providers: 4, want: 3},
+		{name: "five providers", providers: 5, want: 3},
+		{name: "method quorum", quorum: 4, providers: 5, want: 4},
+		{name: "method quorum above the providers", quorum: 6, providers: 5, want: 6},
+		{name: "method quorum below two", quorum: 1, providers: 5, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quorum(model.Method{ConsensusQuorum: tt.quorum}, tt.providers); got != tt.want {
+				t.Errorf("quorum() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsensusAnswer(t *testing.T) {
+	decode := func(document string) (value any) {
+		if err := json.Unmarshal([]byte(document), &value); err != nil {
+			t.Fatalf("invalid test document %s - %v", document, err)
+		}
+		return
+	}
+	tests := []struct {
+		name  string
+		keys  model.Fields
+		a, b  string
+		agree bool
+	}{
+		{name: "whole result equal", a: `{"closed":true,"reason":"works"}`, b: `{"reason":"works","closed":true}`, agree: true},
+		{name: "whole result different", a: `{"closed":true,"reason":"works"}`, b: `{"closed":true,"reason":"flood"}`, agree: false},
+		{name: "keys equal", keys: model.Fields{"closed"}, a: `{"closed":true,"reason":"works"}`, b: `{"closed":true,"reason":"flood"}`, agree: true},
+		{name: "keys different", keys: model.Fields{"closed"}, a: `{"closed":true}`, b: `{"closed":false}`, agree: false},
+		{name: "nested keys equal", keys: model.Fields{"closure.status", "id"}, a: `{"id":"BR-101","closure":{"status":"closed","at":1}}`, b: `{"closure":{"status":"closed","at":2},"id":"BR-101"}`, agree: true},
+		{name: "nested keys different", keys: model.Fields{"closure.status"}, a: `{"closure":{"status":"closed"}}`, b: `{"closure":{"status":"open"}}`, agree: false},
+		{name: "key missing on both", keys: model.Fields{"closed"}, a: `{"reason":"works"}`, b: `{}`, agree: true},
+		{name: "null and false", keys: model.Fields{"closed"}, a: `{"closed":null}`, b: `{"closed":false}`, agree: false},
+		{name: "missing and null", keys: model.Fields{"closed"}, a: `{}`, b: `{"closed":null}`, agree: true},
+		{name: "results not objects", keys: model.Fields{"closed"}, a: `[1]`, b: `"closed"`, agree: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := model.Method{ConsensusKeys: tt.keys}
+			a, err := consensusAnswer(method, decode(tt.a))
+			if err != nil {
+				t.Fatalf("consensusAnswer() error = %v", err)
+			}
+			b, err := consensusAnswer(method, decode(tt.b))
+			if err != nil {
+				t.Fatalf("consensusAnswer() error = %v", err)
+			}
+			if (a == b) != tt.agree {
+				t.Errorf("consensusAnswer() = %s and %s, want agreement %v", a, b, tt.agree)
+			}
+		})
+	}
+}
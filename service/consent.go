@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/internal/validate"
+	"github.com/caioeverest/fed-its/model"
+	"gorm.io/gorm"
+)
+
+type ConsentI interface {
+	Grant(ctx context.Context, user model.User, grant model.ConsentGrant) (model.Consent, error)
+	Revoke(ctx context.Context, user model.User, provider, method string) error
+	List(ctx context.Context, user model.User) ([]model.Consent, error)
+}
+
+type Consent struct {
+	cfg      *config.Config
+	log      *logger.Logger
+	db       *database.Database
+	validate *validate.Validate
+}
+
+func NewConsent(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate) ConsentI {
+	return &Consent{cfg, log, db, validate}
+}
+
+// Grant godoc
+// @Summary Grant consent to a provider
+// @Description Allow a provider to receive the user's calls of a method until the grant expires
+func (c *Consent) Grant(ctx context.Context, user model.User, grant model.ConsentGrant) (consent model.Consent, err error) {
+	var (
+		provider model.Provider
+		method   model.Method
+	)
+	c.log.Infof("User %s requested to grant consent to provider %s on method %s", user.Ref, grant.Provider, grant.Method)
+
+	//Validate input
+	if err = c.validate.Struct(grant); err != nil {
+		c.log.Errorf("Validation error: %+v", err)
+		return
+	}
+	if grant.ExpiresAt != nil && grant.ExpiresAt.Before(time.Now()) {
+		c.log.Errorf("Consent expiration %s is in the past", grant.ExpiresAt)
+		return consent, itserrors.ErrValidation.WithMessage("expires_at must be in the future")
+	}
+	if provider, method, err = c.lookup(ctx, grant.Provider, grant.Method); err != nil {
+		return
+	}
+
+	//Create or refresh grant
+	consent = model.Consent{UserID: user.ID, ProviderID: provider.ID, MethodID: method.ID}
+	if err = c.db.WithContext(ctx).
+		Where(&consent).
+		Assign(map[string]any{"expires_at": grant.ExpiresAt}).
+		FirstOrCreate(&consent).Error; err != nil {
+		c.log.Errorf("Error granting consent - %+v", err)
+		return
+	}
+	consent.ExpiresAt = grant.ExpiresAt
+	consent.Provider, consent.Method = provider.Slug, method.Name
+
+	c.log.Infof("User %s granted consent to provider %s on method %s", user.Ref, provider.Slug, method.Name)
+	return consent, nil
+}
+
+// Revoke godoc
+// @Summary Revoke consent from a provider
+// @Description Stop a provider from receiving the user's calls of a method
+func (c *Consent) Revoke(ctx context.Context, user model.User, providerSlug, methodName string) (err error) {
+	var (
+		provider model.Provider
+		method   model.Method
+	)
+	c.log.Infof("User %s requested to revoke consent from provider %s on method %s", user.Ref, providerSlug, methodName)
+
+	if provider, method, err = c.lookup(ctx, providerSlug, methodName); err != nil {
+		return
+	}
+
+	result := c.db.WithContext(ctx).
+		Where("user_id = ? AND provider_id = ?", user.ID, provider.ID).
+		Where("method_id IN (?)", sameMethod(c.db.DB, method)).
+		Delete(&model.Consent{})
+	if err = result.Error; err != nil {
+		c.log.Errorf("Error revoking consent - %+v", err)
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.log.Errorf("User %s has no consent for provider %s on method %s", user.Ref, providerSlug, methodName)
+		return itserrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// List godoc
+// @Summary List consents
+// @Description List every consent the user has granted, including expired ones
+func (c *Consent) List(ctx context.Context, user model.User) (list []model.Consent, err error) {
+	c.log.Infof("List consents of user %s", user.Ref)
+	if err = c.db.WithContext(ctx).
+		Select("consents.*, providers.slug AS provider, methods.name AS method").
+		Joins("JOIN providers ON providers.id = consents.provider_id").
+		Joins("JOIN methods ON methods.id = consents.method_id").
+		Where("consents.user_id = ?", user.ID).
+		Find(&list).Error; err != nil {
+		c.log.Errorf("Error listing consents - %+v", err)
+		return
+	}
+	c.log.Infof("User %s has %d consents", user.Ref, len(list))
+	return
+}
+
+func (c *Consent) lookup(ctx context.Context, providerSlug, methodName string) (provider model.Provider, method model.Method, err error) {
+	if err = c.db.WithContext(ctx).Where("slug = ?", providerSlug).First(&provider).Error; err != nil {
+		c.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+	if err = c.db.WithContext(ctx).Where("name = ?", methodName).First(&method).Error; err != nil {
+		c.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+	return
+}
+
+// consented is a scope that keeps only the providers the user allowed to receive calls of
+// the method. A consent covers every version of the method, a provider with consents to
+// several versions is still kept once.
+func consented(userRef string, method model.Method) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		consents := db.Session(&gorm.Session{NewDB: true}).
+			Table("consents").
+			Select("1").
+			Joins("JOIN users ON users.id = consents.user_id").
+			Where("consents.provider_id = providers.id AND consents.method_id IN (?)", sameMethod(db, method)).
+			Where("users.ref = ?", userRef).
+			Where("(consents.expires_at IS NULL OR consents.expires_at > ?)", time.Now())
+		return db.Where("EXISTS (?)", consents)
+	}
+}
+
+// sameMethod selects the IDs of every version of the method, deleted ones included, so
+// consents given to any of them still count
+func sameMethod(db *gorm.DB, method model.Method) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&model.Method{}).Select("id").Where("name = ?", method.Name)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/schema"
+	"github.com/caioeverest/fed-its/model"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/imroc/req/v3"
+)
+
+// Continue godoc
+// @Summary Continue an exchange
+// @Description Run the next round of an exchange with the provider that answered its first round
+func (o *Orquestrator) Continue(ctx context.Context, userRef, token string, params []any) (result model.Envelope, err error) {
+	var (
+		state    model.ExchangeState
+		method   model.Method
+		provider model.Provider
+	)
+	o.log.Infof("Continue exchange %s requested by user %s", token, userRef)
+
+	if state, err = o.loadExchange(ctx, token); err != nil {
+		o.log.Errorf("Error loading exchange %s: %+v", token, err)
+		return
+	}
+	if state.UserRef != userRef {
+		o.log.Errorf("Exchange %s does not belong to user %s", token, userRef)
+		return result, itserrors.ErrNotFound
+	}
+	if method, err = findMethod(ctx, o.db, state.Method, state.Version); err != nil {
+		o.log.Errorf("Error getting method of exchange %s: %+v", token, err)
+		return
+	}
+	if method.Sunset(time.Now()) {
+		o.log.Errorf("Method %s of exchange %s reached its sunset date %s", method.Name, token, method.SunsetAt)
+		return result, itserrors.ErrMethodSunset
+	}
+	if err = o.db.WithContext(ctx).
+		Where("slug = ?", state.Provider).
+		Scopes(approved, consented(userRef, method)).
+		First(&provider).Error; err != nil {
+		o.log.Errorf("Error getting provider of exchange %s: %+v", token, err)
+		return
+	}
+
+	state.Step++
+	if err = o.claimRound(ctx, state); err != nil {
+		o.log.Errorf("Error claiming step %d of exchange %s: %+v", state.Step, token, err)
+		return
+	}
+	if result, err = o.exchangeRound(ctx, method, provider, state, params); err != nil {
+		o.releaseRound(state)
+		return
+	}
+	result.Version = method.Version
+	result.Deprecation = method.Deprecation()
+	return
+}
+
+// claimRound makes sure a step of an exchange runs once, concurrent requests with the
+// same token would otherwise call the provider twice for the same round. The claim is
+// kept once the round succeeds, so a request that loaded the exchange before the step
+// advanced cannot run it again.
+func (o *Orquestrator) claimRound(ctx context.Context, state model.ExchangeState) error {
+	claimed, err := o.redis.SetNX(ctx, roundKey(state), state.UserRef, o.conf.ExchangeTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return itserrors.ErrConflict.WithMessage(fmt.Sprintf("Step %d of the exchange is already running", state.Step))
+	}
+	return nil
+}
+
+// releaseRound frees the step of a failed round, so the user can try it again
+func (o *Orquestrator) releaseRound(state model.ExchangeState) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := o.redis.Del(ctx, roundKey(state)).Err(); err != nil {
+		o.log.Errorf("Error releasing step %d of exchange %s: %+v", state.Step, state.Token, err)
+	}
+}
+
+// handleExchange starts a new exchange with the first provider that answers its first round
+func (o *Orquestrator) handleExchange(ctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) (result model.Envelope, err error) {
+	var token string
+	if token, err = newToken(16); err != nil {
+		o.log.Errorf("Error generating exchange token: %+v", err)
+		return
+	}
+
+	for _, provider := range listOfProviders {
+		state := model.ExchangeState{
+			ExchangeStep: model.ExchangeStep{Token: token, Step: 1},
+			Method:       method.Name,
+			Version:      method.Version,
+			Provider:     provider.Slug,
+			UserRef:      userRef,
+		}
+		if result, err = o.exchangeRound(ctx, method, provider, state, params); err != nil {
+			o.log.Errorf("Got an error from provider: %+v", err)
+			continue
+		}
+		return
+	}
+
+	return result, itserrors.ErrNoProviderAnswered.WithMessage(fmt.Sprintf("no provider could handle the request, last error: %v", err))
+}
+
+// exchangeRound calls the provider for the current step of the exchange and keeps the
+// state around for the next one unless the provider says the exchange is done. Only the
+// final result of an exchange is checked against the method result structure.
+func (o *Orquestrator) exchangeRound(ctx context.Context, method model.Method, provider model.Provider, state model.ExchangeState, params []any) (result model.Envelope, err error) {
+	var (
+		response *req.Response
+		reply    model.ExchangeReply
+	)
+	o.log.Infof("Calling provider %s for step %d of exchange %s", provider.Slug, state.Step, state.Token)
+
+	// Rounds are never retried, a round may have side effects like confirming a booking
+	policy := o.callPolicy(ctx, method, provider)
+	if response, err = o.attempt(ctx, policy, method, provider, func(ctx context.Context) (*req.Response, error) {
+		return provider.CallProviderExchange(ctx, o.keyring, state.UserRef, state.Method, state.Version, params, state.ExchangeStep)
+	}); err != nil {
+		return
+	}
+	if err = response.Unmarshal(&reply); err != nil {
+		return
+	}
+	if reply.Done {
+		if err = schema.Validate(method.ResultStructure, reply.Result); err != nil {
+			o.log.Warnf("Provider %s ended exchange %s with a non-conforming result: %+v", provider.Slug, state.Token, err)
+			failure := itserrors.ErrInvalidResult.WithDetails(err)
+			failure.Provider = provider.Slug
+			return result, failure
+		}
+	}
+
+	state.Done = reply.Done
+	if state.Done {
+		err = o.redis.Del(ctx, exchangeKey(state.Token)).Err()
+	} else {
+		err = o.saveExchange(ctx, state)
+	}
+	if err != nil {
+		o.log.Errorf("Error storing exchange %s: %+v", state.Token, err)
+		return
+	}
+
+	step := state.ExchangeStep
+	return model.Envelope{
+		Provider: provider.Name,
+		Result:   reply.Result,
+		Exchange: &step,
+	}, nil
+}
+
+func (o *Orquestrator) loadExchange(ctx context.Context, token string) (state model.ExchangeState, err error) {
+	var bytes []byte
+	if bytes, err = o.redis.Get(ctx, exchangeKey(token)).Bytes(); err != nil {
+		if errors.Is(err, goredis.Nil) {
+			err = itserrors.ErrNotFound
+		}
+		return
+	}
+	err = json.Unmarshal(bytes, &state)
+	return
+}
+
+func (o *Orquestrator) saveExchange(ctx context.Context, state model.ExchangeState) (err error) {
+	var bytes []byte
+	if bytes, err = json.Marshal(state); err != nil {
+		return
+	}
+	return o.redis.Set(ctx, exchangeKey(state.Token), bytes, o.conf.ExchangeTTL).Err()
+}
+
+func exchangeKey(token string) string {
+	return fmt.Sprintf("exchange:%s", token)
+}
+
+func roundKey(state model.ExchangeState) string {
+	return fmt.Sprintf("lock:exchange:%s:%d", state.Token, state.Step)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/imroc/req/v3"
+)
+
+// providerFailure turns a non-2xx answer of a provider into an itserrors.Error, reading
+// the provider error contract from the body when there is one. Unless the provider says
+// otherwise, server errors and rate limits are retryable.
+func providerFailure(provider model.Provider, response *req.Response) error {
+	var (
+		contract model.ProviderError
+		body     struct {
+			Error json.RawMessage `json:"error"`
+		}
+		retryable = response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests
+		message   = fmt.Sprintf("provider %s answered with status %d", provider.Slug, response.StatusCode)
+	)
+
+	if err := response.Unmarshal(&body); err == nil && len(body.Error) > 0 {
+		if err = json.Unmarshal(body.Error, &contract); err != nil {
+			_ = json.Unmarshal(body.Error, &contract.Message)
+		}
+	}
+	if contract.Retryable != nil {
+		retryable = *contract.Retryable
+	}
+	switch {
+	case contract.Code != "" && contract.Message != "":
+		message = fmt.Sprintf("%s: [%s] %s", message, contract.Code, contract.Message)
+	case contract.Message != "":
+		message = fmt.Sprintf("%s: %s", message, contract.Message)
+	}
+
+	failure := itserrors.ErrProviderFailed.WithMessage(message)
+	failure.Provider = provider.Slug
+	failure.Retryable = retryable
+	return failure
+}
+
+// asProviderError describes any error of a call to a provider as an itserrors.Error
+func asProviderError(provider model.Provider, err error) *itserrors.Error {
+	var failure itserrors.Error
+	switch {
+	case errors.As(err, &failure):
+	case errors.Is(err, context.DeadlineExceeded):
+		failure = itserrors.ErrProviderTimeout
+	default:
+		failure = itserrors.ErrProviderUnreachable.WithMessage(fmt.Sprintf("provider %s could not be reached: %v", provider.Slug, err))
+	}
+	failure.Provider = provider.Slug
+	return &failure
+}
+
+// retryable tells if a call failed for a reason that may go away on a new attempt, that is
+// a connection error, a timeout or a failure the provider flagged as retryable
+func retryable(err error) bool {
+	var failure itserrors.Error
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &failure):
+		return failure.Retryable
+	}
+	return true
+}
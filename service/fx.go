@@ -6,5 +6,13 @@ func Services() fx.Option {
 	return fx.Provide(
 		NewProvider,
 		NewMethod,
+		NewOrquestrator,
+		NewUser,
+		NewConsent,
+		NewHealth,
+		NewBreaker,
+		NewStats,
+		NewSelector,
+		NewJob,
 	)
 }
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+	"go.uber.org/fx"
+)
+
+const healthProbeLock = "lock:health:probe"
+
+type HealthI interface {
+	Get(ctx context.Context, slug string) (model.ProviderHealth, error)
+	List(ctx context.Context) ([]model.ProviderHealth, error)
+	Available(ctx context.Context, providers []model.Provider) []Candidate
+}
+
+type Health struct {
+	cfg    *config.Config
+	log    *logger.Logger
+	db     *database.Database
+	redis  *redis.Client
+	client *req.Client
+}
+
+// NewHealth builds the health service and starts probing every provider in background
+// for as long as the Fx application runs
+func NewHealth(lc fx.Lifecycle, cfg *config.Config, log *logger.Logger, db *database.Database, redis *redis.Client) HealthI {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		h           = &Health{cfg, log, db, redis, req.C().SetTimeout(cfg.Health.Timeout)}
+	)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go h.run(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+	return h
+}
+
+// Get godoc
+// @Summary Get provider health
+// @Description Get the health of a provider computed from its latest probes
+func (h *Health) Get(ctx context.Context, slug string) (health model.ProviderHealth, err error) {
+	var provider model.Provider
+	h.log.Infof("Get health of provider %s", slug)
+	if err = h.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		h.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+	return h.status(ctx, slug)
+}
+
+// List godoc
+// @Summary List providers health
+// @Description List the health of every provider of the federation
+func (h *Health) List(ctx context.Context) (list []model.ProviderHealth, err error) {
+	var providers []model.Provider
+	h.log.Info("List health of every provider")
+	if err = h.db.WithContext(ctx).Find(&providers).Error; err != nil {
+		h.log.Errorf("Error listing providers - %+v", err)
+		return
+	}
+
+	list = make([]model.ProviderHealth, 0, len(providers))
+	for _, provider := range providers {
+		var health model.ProviderHealth
+		if health, err = h.status(ctx, provider.Slug); err != nil {
+			h.log.Errorf("Error getting health of provider %s - %+v", provider.Slug, err)
+			return
+		}
+		list = append(list, health)
+	}
+	return
+}
+
+// Available removes the providers that are down and returns the others as candidates
+// with their health, the selector keeps the degraded ones last. When every provider is
+// down they are all kept, so a broken health probe never stops the federation from
+// answering.
+func (h *Health) Available(ctx context.Context, providers []model.Provider) []Candidate {
+	var candidates, down []Candidate
+	for _, provider := range providers {
+		health, err := h.status(ctx, provider.Slug)
+		if err != nil {
+			h.log.Errorf("Error getting health of provider %s - %+v", provider.Slug, err)
+		}
+		candidate := Candidate{Provider: provider, Health: health}
+		if health.Status == model.HealthDown {
+			h.log.Warnf("Skipping unhealthy provider %s", provider.Slug)
+			down = append(down, candidate)
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if len(candidates) == 0 {
+		h.log.Warnf("Every provider is unhealthy, calling them anyway")
+		return down
+	}
+	return candidates
+}
+
+func (h *Health) run(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.Health.Interval)
+	defer ticker.Stop()
+	for {
+		h.probeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeAll probes every provider, unless another instance already did it on this interval.
+// Probes run concurrently, at most Health.Concurrency at once and each bounded by
+// Health.Timeout, so the round ends well before the lock expires.
+func (h *Health) probeAll(ctx context.Context) {
+	var (
+		providers   []model.Provider
+		wg          sync.WaitGroup
+		slots       = make(chan struct{}, lo.Max([]int{h.cfg.Health.Concurrency, 1}))
+		hostname, _ = os.Hostname()
+	)
+	if acquired, err := h.redis.SetNX(ctx, healthProbeLock, hostname, h.cfg.Health.Interval).Result(); err != nil || !acquired {
+		if err != nil {
+			h.log.Errorf("Error acquiring health probe lock - %+v", err)
+		}
+		return
+	}
+	if err := h.db.WithContext(ctx).Find(&providers).Error; err != nil {
+		h.log.Errorf("Error listing providers to probe - %+v", err)
+		return
+	}
+	for _, provider := range providers {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(provider model.Provider) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			probeCtx, cancel := context.WithTimeout(ctx, h.cfg.Health.Timeout)
+			defer cancel()
+			if err := h.record(ctx, provider.Slug, h.probe(probeCtx, provider)); err != nil {
+				h.log.Errorf("Error recording health of provider %s - %+v", provider.Slug, err)
+			}
+		}(provider)
+	}
+	wg.Wait()
+}
+
+// probe calls the provider health URL, expecting a success status, or, when the
+// provider has no health URL, checks that its webhook is reachable
+func (h *Health) probe(ctx context.Context, provider model.Provider) (sample model.HealthSample) {
+	var (
+		response *req.Response
+		err      error
+		start    = time.Now()
+	)
+	if provider.HealthURL != "" {
+		if response, err = h.client.R().SetContext(ctx).Get(provider.HealthURL); err == nil && !response.IsSuccessState() {
+			err = fmt.Errorf("health check answered with status %d", response.StatusCode)
+		}
+	} else {
+		if response, err = h.client.R().SetContext(ctx).Head(provider.Webhook); err == nil && response.StatusCode >= 500 {
+			err = fmt.Errorf("webhook answered with status %d", response.StatusCode)
+		}
+	}
+
+	sample = model.HealthSample{Success: err == nil, Latency: time.Since(start), At: start}
+	if err != nil {
+		sample.Error = err.Error()
+	}
+	return
+}
+
+// record keeps the sample in the provider rolling window and stores its new status
+func (h *Health) record(ctx context.Context, slug string, sample model.HealthSample) (err error) {
+	var (
+		bytes   []byte
+		samples []model.HealthSample
+		health  model.ProviderHealth
+	)
+	if bytes, err = json.Marshal(sample); err != nil {
+		return
+	}
+	pipe := h.redis.TxPipeline()
+	pipe.LPush(ctx, healthSamplesKey(slug), bytes)
+	pipe.LTrim(ctx, healthSamplesKey(slug), 0, int64(h.cfg.Health.Window-1))
+	if _, err = pipe.Exec(ctx); err != nil {
+		return
+	}
+
+	if samples, err = h.samples(ctx, slug); err != nil {
+		return
+	}
+	health = h.summarize(slug, samples)
+	if bytes, err = json.Marshal(health); err != nil {
+		return
+	}
+	if health.Status == model.HealthDown {
+		h.log.Warnf("Provider %s is down - %s", slug, health.LastError)
+	}
+	return h.redis.Set(ctx, healthKey(slug), bytes, 0).Err()
+}
+
+// status is the health of the provider computed from its latest probes, without checking
+// the provider exists
+func (h *Health) status(ctx context.Context, slug string) (health model.ProviderHealth, err error) {
+	var bytes []byte
+	if bytes, err = h.redis.Get(ctx, healthKey(slug)).Bytes(); err != nil {
+		if errors.Is(err, goredis.Nil) {
+			err = nil
+		}
+		return model.ProviderHealth{Provider: slug, Status: model.HealthUnknown}, err
+	}
+	err = json.Unmarshal(bytes, &health)
+	return
+}
+
+func (h *Health) samples(ctx context.Context, slug string) (samples []model.HealthSample, err error) {
+	var raw []string
+	if raw, err = h.redis.LRange(ctx, healthSamplesKey(slug), 0, -1).Result(); err != nil {
+		return
+	}
+	samples = make([]model.HealthSample, 0, len(raw))
+	for _, item := range raw {
+		var sample model.HealthSample
+		if err = json.Unmarshal([]byte(item), &sample); err != nil {
+			return
+		}
+		samples = append(samples, sample)
+	}
+	return
+}
+
+// summarize computes the status of a provider from its samples, newest first
+func (h *Health) summarize(slug string, samples []model.HealthSample) (health model.ProviderHealth) {
+	health = model.ProviderHealth{Provider: slug, Status: model.HealthUnknown, Samples: len(samples)}
+	if len(samples) == 0 {
+		return
+	}
+
+	succeeded := lo.Filter(samples, func(sample model.HealthSample, _ int) bool { return sample.Success })
+	health.SuccessRate = float64(len(succeeded)) / float64(len(samples))
+	health.LastCheck = samples[0].At
+	if failed, found := lo.Find(samples, func(sample model.HealthSample) bool { return !sample.Success }); found {
+		health.LastError = failed.Error
+	}
+	if len(succeeded) > 0 {
+		total := lo.SumBy(succeeded, func(sample model.HealthSample) time.Duration { return sample.Latency })
+		health.AvgLatency = (total / time.Duration(len(succeeded))).Milliseconds()
+	}
+
+	switch {
+	case health.SuccessRate < h.cfg.Health.DownSuccessRate:
+		health.Status = model.HealthDown
+	case health.SuccessRate < h.cfg.Health.MinSuccessRate, time.Duration(health.AvgLatency)*time.Millisecond > h.cfg.Health.MaxLatency:
+		health.Status = model.HealthDegraded
+	default:
+		health.Status = model.HealthUp
+	}
+	return
+}
+
+func healthKey(slug string) string {
+	return fmt.Sprintf("health:%s", slug)
+}
+
+func healthSamplesKey(slug string) string {
+	return fmt.Sprintf("health:%s:samples", slug)
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// jobCancelChannel is where a canceled job is announced, so the instance running it stops it
+const jobCancelChannel = "job:cancel"
+
+// jobSaveTimeout bounds the write of the outcome of a job, which must happen even when
+// the application is stopping
+const jobSaveTimeout = 5 * time.Second
+
+// JobRun is the call an asynchronous job runs, it must stop when the context is canceled
+type JobRun func(ctx context.Context) (model.Envelope, error)
+
+type JobI interface {
+	Submit(ctx context.Context, userRef, method string, run JobRun) (model.Job, error)
+	Get(ctx context.Context, userRef, id string) (model.Job, error)
+	Cancel(ctx context.Context, userRef, id string) (model.Job, error)
+}
+
+type Job struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	redis   *redis.Client
+	ctx     context.Context
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// NewJob builds the job service. Jobs run in background for as long as the Fx application
+// runs, stopping it cancels the running jobs and waits for them to save their outcome.
+func NewJob(lc fx.Lifecycle, cfg *config.Config, log *logger.Logger, redis *redis.Client) JobI {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		j           = &Job{cfg: cfg, log: log, redis: redis, ctx: ctx, running: map[string]context.CancelFunc{}}
+	)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go j.listen(ctx)
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			// Canceled under the lock, so no job is added once the wait begins
+			j.mu.Lock()
+			cancel()
+			j.mu.Unlock()
+			done := make(chan struct{})
+			go func() {
+				j.wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+				j.log.Warn("Stopped before every job saved its outcome")
+			}
+			return nil
+		},
+	})
+	return j
+}
+
+// Submit stores a pending job and runs it in background, returning at once. Jobs are
+// refused once the application is stopping.
+func (j *Job) Submit(ctx context.Context, userRef, method string, run JobRun) (job model.Job, err error) {
+	var (
+		state model.JobState
+		id    string
+		now   = time.Now().UTC()
+	)
+
+	//Count the job before the stop waits for the running ones
+	j.mu.Lock()
+	if j.ctx.Err() != nil {
+		j.mu.Unlock()
+		j.log.Errorf("Job submitted by user %s while stopping", userRef)
+		return job, itserrors.ErrInternal.WithMessage("The server is stopping, try again")
+	}
+	j.wg.Add(1)
+	j.mu.Unlock()
+
+	if id, err = newToken(16); err != nil {
+		j.log.Errorf("Error generating job id - %+v", err)
+		j.wg.Done()
+		return
+	}
+	state = model.JobState{
+		Job:     model.Job{ID: id, Status: model.JobPending, Method: method, CreatedAt: now, UpdatedAt: now},
+		UserRef: userRef,
+	}
+	if err = j.save(ctx, state); err != nil {
+		j.log.Errorf("Error saving job %s - %+v", id, err)
+		j.wg.Done()
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(j.ctx)
+	j.mu.Lock()
+	j.running[id] = cancel
+	j.mu.Unlock()
+	go j.run(runCtx, id, run)
+
+	j.log.Infof("Job %s submitted by user %s", id, userRef)
+	return state.Job, nil
+}
+
+// Get the job of the user
+func (j *Job) Get(ctx context.Context, userRef, id string) (job model.Job, err error) {
+	var state model.JobState
+	j.log.Infof("Get job %s requested by user %s", id, userRef)
+	if state, err = j.load(ctx, userRef, id); err != nil {
+		j.log.Errorf("Error loading job %s - %+v", id, err)
+		return
+	}
+	return state.Job, nil
+}
+
+// Cancel a job of the user that did not finish yet, the instance running it is told to stop it
+func (j *Job) Cancel(ctx context.Context, userRef, id string) (job model.Job, err error) {
+	var state model.JobState
+	j.log.Infof("Cancel job %s requested by user %s", id, userRef)
+
+	//Search for job
+	if state, err = j.load(ctx, userRef, id); err != nil {
+		j.log.Errorf("Error loading job %s - %+v", id, err)
+		return
+	}
+	if state.Status.Finished() {
+		j.log.Errorf("Job %s already finished as %s", id, state.Status)
+		return state.Job, itserrors.ErrConflict.WithMessage(fmt.Sprintf("Job already finished as %s", state.Status))
+	}
+
+	//Cancel job
+	if state, err = j.update(ctx, id, func(state *model.JobState) { state.Status = model.JobCanceled }); err != nil {
+		j.log.Errorf("Error canceling job %s - %+v", id, err)
+		return
+	}
+	if state.Status != model.JobCanceled {
+		j.log.Errorf("Job %s finished as %s before it was canceled", id, state.Status)
+		return state.Job, itserrors.ErrConflict.WithMessage(fmt.Sprintf("Job already finished as %s", state.Status))
+	}
+	if err = j.redis.Publish(ctx, jobCancelChannel, id).Err(); err != nil {
+		j.log.Errorf("Error announcing the cancellation of job %s - %+v", id, err)
+		return
+	}
+
+	j.log.Infof("Job %s canceled", id)
+	return state.Job, nil
+}
+
+func (j *Job) run(ctx context.Context, id string, run JobRun) {
+	defer j.wg.Done()
+	defer j.stop(id)
+
+	if _, err := j.update(ctx, id, func(state *model.JobState) { state.Status = model.JobRunning }); err != nil {
+		j.log.Errorf("Error starting job %s - %+v", id, err)
+	}
+	result, err := run(ctx)
+
+	saveCtx, cancel := context.WithTimeout(context.Background(), jobSaveTimeout)
+	defer cancel()
+	state, saveErr := j.update(saveCtx, id, func(state *model.JobState) {
+		switch {
+		case err == nil:
+			state.Status, state.Result = model.JobDone, &result
+		case j.ctx.Err() != nil:
+			failure := itserrors.ErrInternal.WithMessage("The server stopped before the job finished")
+			state.Status, state.Error = model.JobFailed, &failure
+		default:
+			failure := jobError(err)
+			state.Status, state.Error = model.JobFailed, &failure
+		}
+	})
+	if saveErr != nil {
+		j.log.Errorf("Error saving the outcome of job %s - %+v", id, saveErr)
+		return
+	}
+	j.log.Infof("Job %s finished as %s", id, state.Status)
+}
+
+// listen stops the jobs of this instance canceled through any instance
+func (j *Job) listen(ctx context.Context) {
+	sub := j.redis.Subscribe(ctx, jobCancelChannel)
+	defer sub.Close()
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			j.stop(message.Payload)
+		}
+	}
+}
+
+// stop cancels the job when it runs on this instance
+func (j *Job) stop(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cancel, found := j.running[id]; found {
+		cancel()
+		delete(j.running, id)
+	}
+}
+
+func (j *Job) load(ctx context.Context, userRef, id string) (state model.JobState, err error) {
+	var bytes []byte
+	if bytes, err = j.redis.Get(ctx, jobKey(id)).Bytes(); err != nil {
+		if errors.Is(err, goredis.Nil) {
+			err = itserrors.ErrNotFound
+		}
+		return
+	}
+	if err = json.Unmarshal(bytes, &state); err != nil {
+		return
+	}
+	if state.UserRef != userRef {
+		return model.JobState{}, itserrors.ErrNotFound
+	}
+	return
+}
+
+func (j *Job) save(ctx context.Context, state model.JobState) (err error) {
+	var bytes []byte
+	if bytes, err = json.Marshal(state); err != nil {
+		return
+	}
+	return j.redis.Set(ctx, jobKey(state.ID), bytes, j.cfg.JobTTL).Err()
+}
+
+// update changes a job that did not finish yet, a finished job is returned unchanged.
+// The job is watched so a cancellation is never overwritten by a concurrent change.
+func (j *Job) update(ctx context.Context, id string, change func(state *model.JobState)) (state model.JobState, err error) {
+	const attempts = 3
+	for attempt := 0; attempt < attempts; attempt++ {
+		err = j.redis.Watch(ctx, func(tx *goredis.Tx) error {
+			bytes, err := tx.Get(ctx, jobKey(id)).Bytes()
+			if err != nil {
+				return err
+			}
+			if err = json.Unmarshal(bytes, &state); err != nil {
+				return err
+			}
+			if state.Status.Finished() {
+				return nil
+			}
+			change(&state)
+			state.UpdatedAt = time.Now().UTC()
+			if bytes, err = json.Marshal(state); err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+				return pipe.Set(ctx, jobKey(id), bytes, j.cfg.JobTTL).Err()
+			})
+			return err
+		}, jobKey(id))
+		if !errors.Is(err, goredis.TxFailedErr) {
+			break
+		}
+	}
+	if errors.Is(err, goredis.Nil) {
+		err = itserrors.ErrNotFound
+	}
+	return
+}
+
+// jobError is the error a failed job reports, like the HTTP error of a synchronous call
+func jobError(err error) itserrors.Error {
+	var itsErr itserrors.Error
+	switch {
+	case errors.As(err, &itsErr):
+		return itsErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return itserrors.ErrNotFound
+	}
+	return itserrors.ErrInternal
+}
+
+func jobKey(id string) string {
+	return fmt.Sprintf("job:%s", id)
+}
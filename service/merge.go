@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/schema"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/samber/lo"
+)
+
+// mergeResults combines the results of the providers, in provider order, into one.
+// Objects are merged key by key keeping the first non-empty value, then every merge
+// rule replaces its field with the combination of the values the providers answered.
+func mergeResults(rules model.MergeRules, results []any) any {
+	merged := mergeDefault(results)
+	for _, rule := range rules {
+		values := make([]any, 0, len(results))
+		for _, result := range results {
+			if value, found := schema.Field(result, rule.Field); found {
+				values = append(values, value)
+			}
+		}
+		if value, ok := applyRule(rule, values); ok {
+			merged = setField(merged, strings.Split(rule.Field, "."), value)
+		}
+	}
+	return merged
+}
+
+func applyRule(rule model.MergeRule, values []any) (any, bool) {
+	switch rule.Strategy {
+	case model.MergeConcat:
+		return concat(values), true
+	case model.MergeDedupe:
+		return dedupe(concat(values), rule.Key), true
+	case model.MergePreferNonEmpty:
+		return preferNonEmpty(values), len(values) > 0
+	case model.MergeMin, model.MergeMax, model.MergeAvg:
+		numbers := lo.FilterMap(values, func(value any, _ int) (float64, bool) {
+			number, ok := value.(float64)
+			return number, ok
+		})
+		if len(numbers) == 0 {
+			return nil, false
+		}
+		switch rule.Strategy {
+		case model.MergeMin:
+			return lo.Min(numbers), true
+		case model.MergeMax:
+			return lo.Max(numbers), true
+		}
+		return lo.Sum(numbers) / float64(len(numbers)), true
+	}
+	return nil, false
+}
+
+// mergeDefault merges objects key by key and keeps the first non-empty value otherwise
+func mergeDefault(values []any) any {
+	nonEmpty := lo.Filter(values, func(value any, _ int) bool { return !empty(value) })
+	if len(nonEmpty) == 0 {
+		return first(values)
+	}
+	objects := lo.FilterMap(nonEmpty, func(value any, _ int) (map[string]any, bool) {
+		object, ok := value.(map[string]any)
+		return object, ok
+	})
+	if len(objects) != len(nonEmpty) {
+		return nonEmpty[0]
+	}
+
+	merged := map[string]any{}
+	for _, object := range objects {
+		for key := range object {
+			if _, done := merged[key]; done {
+				continue
+			}
+			merged[key] = mergeDefault(lo.FilterMap(objects, func(other map[string]any, _ int) (any, bool) {
+				value, ok := other[key]
+				return value, ok
+			}))
+		}
+	}
+	return merged
+}
+
+func preferNonEmpty(values []any) any {
+	if value, found := lo.Find(values, func(value any) bool { return !empty(value) }); found {
+		return value
+	}
+	return first(values)
+}
+
+func first(values []any) any {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func concat(values []any) []any {
+	list := []any{}
+	for _, value := range values {
+		if items, ok := value.([]any); ok {
+			list = append(list, items...)
+		}
+	}
+	return list
+}
+
+// dedupe keeps the first item of each key, items without the key are all kept
+func dedupe(items []any, key string) []any {
+	seen := map[string]bool{}
+	return lo.Filter(items, func(item any, _ int) bool {
+		value, found := item, true
+		if key != "" {
+			value, found = schema.Field(item, key)
+		}
+		if !found {
+			return true
+		}
+		bytes, _ := json.Marshal(value)
+		if seen[string(bytes)] {
+			return false
+		}
+		seen[string(bytes)] = true
+		return true
+	})
+}
+
+// empty reports if the value is null, an empty string, list or object. Numbers and
+// booleans are never empty.
+func empty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// setField returns the value with the field at the path replaced, creating the objects
+// on the path that are missing
+func setField(value any, path []string, field any) any {
+	if len(path) == 0 {
+		return field
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	object[path[0]] = setField(object[path[0]], path[1:], field)
+	return object
+}
+
+// checkResultFields makes sure the consensus keys and the merge rules of the method only
+// point to fields declared in its result structure
+func checkResultFields(method model.Method) error {
+	var violations schema.Violations
+	check := func(path, field string) {
+		if !schema.HasField(method.ResultStructure, field) {
+			violations = append(violations, schema.Violation{
+				Path:    path,
+				Message: fmt.Sprintf("%q is not declared in the result structure", field),
+			})
+		}
+	}
+	for i, key := range method.ConsensusKeys {
+		check(fmt.Sprintf("consensus_keys[%d]", i), key)
+	}
+	for i, rule := range method.MergeRules {
+		check(fmt.Sprintf("merge_rules[%d].field", i), rule.Field)
+	}
+	if len(violations) > 0 {
+		return itserrors.ErrValidation.WithDetails(violations)
+	}
+	return nil
+}
@@ -2,18 +2,32 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/adapter/redis"
 	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
 	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/internal/schema"
 	"github.com/caioeverest/fed-its/internal/validate"
+	"github.com/caioeverest/fed-its/internal/version"
 	"github.com/caioeverest/fed-its/model"
+	"gorm.io/gorm"
 )
 
 type MethodI interface {
 	Create(ctx context.Context, method model.Method) (model.Method, error)
-	Get(ctx context.Context, method string) (model.Method, error)
+	Get(ctx context.Context, method, version string) (model.Method, error)
 	List(ctx context.Context) ([]model.Method, error)
+	Versions(ctx context.Context, method string) ([]model.Method, error)
+	Update(ctx context.Context, method, version string, update model.MethodUpdate) (model.Method, error)
+	Deprecate(ctx context.Context, method, version string, deprecation model.MethodDeprecation) (model.Method, error)
+	Delete(ctx context.Context, method, version string) error
+	InvalidateCache(ctx context.Context, method string, params []any) (int64, error)
 }
 
 type Method struct {
@@ -21,15 +35,20 @@ type Method struct {
 	log      *logger.Logger
 	db       *database.Database
 	validate *validate.Validate
+	redis    *redis.Client
 }
 
-func NewMethod(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate) MethodI {
-	return &Method{cfg, log, db, validate}
+func NewMethod(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate, redis *redis.Client) MethodI {
+	return &Method{cfg, log, db, validate, redis}
 }
 
-// Create a new method in the database and return it
+// Create a new method, or a new version of a method, in the database and return it
 func (m *Method) Create(ctx context.Context, method model.Method) (result model.Method, err error) {
-	m.log.Infof("New method %s requested to be created", method.Name)
+	var existing int64
+	if method.Version == "" {
+		method.Version = model.DefaultVersion
+	}
+	m.log.Infof("New method %s version %s requested to be created", method.Name, method.Version)
 
 	//Validate input
 	m.log.Info("Validating method")
@@ -37,24 +56,49 @@ func (m *Method) Create(ctx context.Context, method model.Method) (result model.
 		m.log.Errorf("Validation error: %+v", err)
 		return
 	}
+	if err = schema.CheckParams(method.Params); err != nil {
+		m.log.Errorf("Validation error: %+v", err)
+		return result, itserrors.ErrInvalidParams.WithDetails(err)
+	}
+	if err = checkResultFields(method); err != nil {
+		m.log.Errorf("Validation error: %+v", err)
+		return
+	}
+
+	//A method is deprecated only through Deprecate
+	method.DeprecatedAt, method.SunsetAt, method.DeprecationMessage = nil, nil, ""
+
+	//Check version
+	if err = m.db.WithContext(ctx).Model(&model.Method{}).Where("name = ? AND version = ?", method.Name, method.Version).Count(&existing).Error; err != nil {
+		m.log.Errorf("Error checking method version - %+v", err)
+		return
+	}
+	if existing > 0 {
+		m.log.Errorf("Method %s version %s already exists", method.Name, method.Version)
+		return result, itserrors.ErrConflict.WithMessage(fmt.Sprintf("Method %s version %s already exists", method.Name, method.Version))
+	}
 
-	//Create method
+	//Create method, the unique index catches a version created since the check
 	if err = m.db.WithContext(ctx).Create(&method).Error; err != nil {
 		m.log.Errorf("Error creating method - %+v", err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return result, itserrors.ErrConflict.WithMessage(fmt.Sprintf("Method %s version %s already exists", method.Name, method.Version))
+		}
 		return
 	}
 
 	return
 }
 
-// Get a method from the database
-func (m *Method) Get(ctx context.Context, methodName string) (method model.Method, err error) {
+// Get the newest version of a method matching the version constraint, the latest
+// stable version by default
+func (m *Method) Get(ctx context.Context, methodName, constraint string) (method model.Method, err error) {
 	m.log.Infof("Get method %s requested", methodName)
-	if err = m.db.WithContext(ctx).Where("name = ?", methodName).First(&method).Error; err != nil {
+	if method, err = findMethod(ctx, m.db, methodName, constraint); err != nil {
 		m.log.Errorf("Error getting method - %+v", err)
 		return
 	}
-	m.log.Infof("Method %s found", methodName)
+	m.log.Infof("Method %s version %s found", methodName, method.Version)
 	return
 }
 
@@ -68,3 +112,171 @@ func (m *Method) List(ctx context.Context) (methods []model.Method, err error) {
 	m.log.Infof("Found %d methods", len(methods))
 	return
 }
+
+// Versions lists every version of a method, newest first
+func (m *Method) Versions(ctx context.Context, methodName string) (methods []model.Method, err error) {
+	m.log.Infof("List versions of method %s requested", methodName)
+	if err = m.db.WithContext(ctx).Where("name = ?", methodName).Find(&methods).Error; err != nil {
+		m.log.Errorf("Error listing method versions - %+v", err)
+		return
+	}
+	if len(methods) == 0 {
+		return methods, itserrors.ErrNotFound
+	}
+	sort.Slice(methods, func(i, j int) bool { return version.Compare(methods[i].Version, methods[j].Version) > 0 })
+	m.log.Infof("Method %s has %d versions", methodName, len(methods))
+	return
+}
+
+// Update the fields of the version of a method that are set and return it. Cached
+// results of the version are removed, they may no longer match the method.
+func (m *Method) Update(ctx context.Context, methodName, constraint string, update model.MethodUpdate) (method model.Method, err error) {
+	m.log.Infof("Update method %s requested", methodName)
+
+	//Search for method
+	if method, err = findMethod(ctx, m.db, methodName, constraint); err != nil {
+		m.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	//Validate input
+	m.log.Info("Validating method")
+	method = update.Apply(method)
+	if err = m.validate.Struct(method); err != nil {
+		m.log.Errorf("Validation error: %+v", err)
+		return
+	}
+	if err = schema.CheckParams(method.Params); err != nil {
+		m.log.Errorf("Validation error: %+v", err)
+		return method, itserrors.ErrInvalidParams.WithDetails(err)
+	}
+	if err = checkResultFields(method); err != nil {
+		m.log.Errorf("Validation error: %+v", err)
+		return
+	}
+
+	//Update method
+	if err = m.db.WithContext(ctx).Save(&method).Error; err != nil {
+		m.log.Errorf("Error updating method - %+v", err)
+		return
+	}
+	m.flushCache(ctx, method)
+
+	m.log.Infof("Method %s version %s updated", methodName, method.Version)
+	return
+}
+
+// Deprecate the version of a method, so every call reports the deprecation, and return
+// it. Once the sunset date is reached the version can no longer be called.
+func (m *Method) Deprecate(ctx context.Context, methodName, constraint string, deprecation model.MethodDeprecation) (method model.Method, err error) {
+	var now = time.Now().UTC()
+	m.log.Infof("Deprecation of method %s requested", methodName)
+
+	//Validate input
+	if deprecation.SunsetAt != nil && deprecation.SunsetAt.Before(now) {
+		m.log.Errorf("Sunset date %s is in the past", deprecation.SunsetAt)
+		return method, itserrors.ErrValidation.WithMessage("sunset_at must be in the future")
+	}
+
+	//Search for method
+	if method, err = findMethod(ctx, m.db, methodName, constraint); err != nil {
+		m.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	//Deprecate method, keeping the date it was first deprecated
+	if method.DeprecatedAt == nil {
+		method.DeprecatedAt = &now
+	}
+	method.SunsetAt = deprecation.SunsetAt
+	method.DeprecationMessage = deprecation.Message
+	if err = m.db.WithContext(ctx).Model(&method).Updates(map[string]any{
+		"deprecated_at":       method.DeprecatedAt,
+		"sunset_at":           method.SunsetAt,
+		"deprecation_message": method.DeprecationMessage,
+	}).Error; err != nil {
+		m.log.Errorf("Error deprecating method - %+v", err)
+		return
+	}
+
+	m.log.Infof("Method %s version %s deprecated", methodName, method.Version)
+	return
+}
+
+// Delete a version of a method that no provider is enrolled in anymore
+func (m *Method) Delete(ctx context.Context, methodName, constraint string) (err error) {
+	var (
+		method   model.Method
+		enrolled int64
+	)
+	m.log.Infof("Delete method %s requested", methodName)
+
+	//Search for method
+	if method, err = findMethod(ctx, m.db, methodName, constraint); err != nil {
+		m.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	//Check enrollments, those of deleted providers do not count
+	if err = m.db.WithContext(ctx).Model(&model.MethodProvider{}).
+		Joins("JOIN providers ON providers.id = method_providers.provider_id").
+		Where("method_providers.method_id = ? AND providers.deleted_at IS NULL", method.ID).
+		Count(&enrolled).Error; err != nil {
+		m.log.Errorf("Error counting enrolled providers - %+v", err)
+		return
+	}
+	if enrolled > 0 {
+		m.log.Errorf("Method %s version %s still has %d enrolled providers", methodName, method.Version, enrolled)
+		return itserrors.ErrMethodInUse.WithDetails(map[string]int64{"enrolled_providers": enrolled})
+	}
+
+	//Delete method
+	if err = m.db.WithContext(ctx).Delete(&method).Error; err != nil {
+		m.log.Errorf("Error deleting method - %+v", err)
+		return
+	}
+	m.flushCache(ctx, method)
+
+	m.log.Infof("Method %s version %s deleted", methodName, method.Version)
+	return
+}
+
+// flushCache removes every cached result of a method version. A failure is only logged,
+// the cached results expire with their TTL anyway.
+func (m *Method) flushCache(ctx context.Context, method model.Method) {
+	if _, err := invalidateCache(ctx, m.redis, fmt.Sprintf("cache:%s:%s:*", method.Name, method.Version)); err != nil {
+		m.log.Errorf("Error flushing cache of method %s version %s - %+v", method.Name, method.Version, err)
+	}
+}
+
+// InvalidateCache removes the cached results of every version of a method. When params
+// is nil every cached result of the method is removed, otherwise only the results of those params.
+func (m *Method) InvalidateCache(ctx context.Context, methodName string, params []any) (removed int64, err error) {
+	var (
+		method  model.Method
+		pattern = fmt.Sprintf("cache:%s:*", methodName)
+	)
+	m.log.Infof("Invalidate cache of method %s requested", methodName)
+	if err = m.db.WithContext(ctx).Where("name = ?", methodName).First(&method).Error; err != nil {
+		m.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	if params != nil {
+		// A shared cache key already uses the wildcard as owner, so it matches every user,
+		// and the wildcard version matches every version
+		method.CachePerUser = false
+		method.Version = "*"
+		if pattern, err = cacheKey(method, "", params); err != nil {
+			m.log.Errorf("Error building cache key - %+v", err)
+			return
+		}
+	}
+
+	if removed, err = invalidateCache(ctx, m.redis, pattern); err != nil {
+		m.log.Errorf("Error invalidating cache - %+v", err)
+		return
+	}
+	m.log.Infof("Removed %d cached results of method %s", removed, methodName)
+	return
+}
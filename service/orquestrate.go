@@ -3,91 +3,190 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/caioeverest/fed-its/adapter/database"
 	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/aes"
 	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
 	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/internal/schema"
 	"github.com/caioeverest/fed-its/model"
 	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
 )
 
 type Orquestrate interface {
-	Request(ctx context.Context, userRef string, method string, params []any) (result model.Envelope, err error)
+	Request(ctx context.Context, userRef string, method string, version string, params []any) (result model.Envelope, err error)
+	Continue(ctx context.Context, userRef string, token string, params []any) (result model.Envelope, err error)
 }
 
 type Orquestrator struct {
-	conf  *config.Config
-	log   *logger.Logger
-	db    *database.Database
-	redis *redis.Client
+	conf     *config.Config
+	log      *logger.Logger
+	db       *database.Database
+	redis    *redis.Client
+	keyring  *aes.Keyring
+	health   HealthI
+	breaker  BreakerI
+	selector SelectorI
+	stats    StatsI
 }
 
-func NewOrquestrator(conf *config.Config, log *logger.Logger, db *database.Database, redis *redis.Client) Orquestrate {
-	return &Orquestrator{conf, log, db, redis}
+func NewOrquestrator(conf *config.Config, log *logger.Logger, db *database.Database, redis *redis.Client, keyring *aes.Keyring, health HealthI, breaker BreakerI, selector SelectorI, stats StatsI) Orquestrate {
+	return &Orquestrator{conf, log, db, redis, keyring, health, breaker, selector, stats}
 }
 
 // Request godoc
 // @Summary Request a method
-// @Description Request a method from a provider or a group of providers and return the first response received
-func (o *Orquestrator) Request(ctx context.Context, userRef string, methodName string, params []any) (result model.Envelope, err error) {
+// @Description Request the newest version of a method matching the version constraint, the latest stable
+// @Description version by default, from a provider or a group of providers and return the first response received
+func (o *Orquestrator) Request(ctx context.Context, userRef string, methodName string, constraint string, params []any) (result model.Envelope, err error) {
 	var (
 		method          model.Method
 		listOfProviders []model.Provider
 	)
 	o.log.Infof("New request received from user %s to call method %s", userRef, methodName)
-	if err = o.db.WithContext(ctx).Where("name = ?", methodName).First(&method).Error; err != nil {
+	if method, err = findMethod(ctx, o.db, methodName, constraint); err != nil {
 		o.log.Errorf("Error while validating request: %+v", err)
 		return
 	}
+	o.log.Infof("Calling version %s of method %s", method.Version, methodName)
+	if method.Sunset(time.Now()) {
+		o.log.Errorf("Method %s reached its sunset date %s", methodName, method.SunsetAt)
+		return result, itserrors.ErrMethodSunset
+	}
 
 	// Validate input
 	o.log.Info("Validating request")
-	// TODO: Validate input
+	if err = schema.ValidateParams(method.Params, params); err != nil {
+		o.log.Errorf("Invalid params for method %s: %+v", methodName, err)
+		return result, itserrors.ErrInvalidParams.WithDetails(err)
+	}
 
-	// Get list of providers
+	// Get list of providers the user consented to
 	o.log.Info("Getting list of providers")
 	if err = o.db.WithContext(ctx).
-		Joins("JOIN provider_methods ON provider_methods.provider_id = providers.id").
-		Joins("JOIN methods ON methods.id = provider_methods.method_id").
-		Where("methods.name = ?", method).
+		Joins("JOIN method_providers ON method_providers.provider_id = providers.id").
+		Where("method_providers.method_id = ?", method.ID).
+		Scopes(approved, consented(userRef, method)).
 		Find(&listOfProviders).Error; err != nil {
 		o.log.Errorf("Error while validating request: %+v", err)
 		return
 	}
 	o.log.Infof("Found %d providers", len(listOfProviders))
+	listOfProviders = o.selector.Select(ctx, method, o.health.Available(ctx, listOfProviders))
+	if len(listOfProviders) == 0 {
+		return result, itserrors.ErrNoProvider
+	}
 
 	switch method.Kind {
 	case model.Broadcast:
-		return o.handleBroadcast(ctx, method, listOfProviders, userRef, params)
+		result, err = o.handleBroadcast(ctx, method, listOfProviders, userRef, params)
 	case model.Concurrent:
-		return o.handleConcurrent(ctx, method, listOfProviders, userRef, params)
-	case model.Exchange, model.Indepotent:
-		return o.handleIndepotent(ctx, method, listOfProviders, userRef, params)
+		result, err = o.handleConcurrent(ctx, method, listOfProviders, userRef, params)
+	case model.Exchange:
+		result, err = o.handleExchange(ctx, method, listOfProviders, userRef, params)
+	case model.Consensus:
+		result, err = o.handleConsensus(ctx, method, listOfProviders, userRef, params)
+	case model.Hedged:
+		result, err = o.handleHedged(ctx, method, listOfProviders, userRef, params)
+	case model.Indepotent:
+		result, err = o.withCache(ctx, method, userRef, params, func() (model.Envelope, error) {
+			return o.handleIndepotent(ctx, method, listOfProviders, userRef, params)
+		})
 	default:
-		return result, errors.New("method kind not implemented")
+		return result, itserrors.ErrInternal.WithMessage(fmt.Sprintf("method kind %s not implemented", method.Kind))
 	}
+	if err == nil {
+		result.Version = method.Version
+		result.Deprecation = method.Deprecation()
+	}
+	return
 }
 
+// handleBroadcast calls every provider and waits for all of them to answer or for the
+// broadcast deadline, returning one envelope per provider. When the method has merge
+// rules the successful results are merged into the result instead, which must match the
+// result structure, and the provider envelopes only report the outcome of each call.
 func (o *Orquestrator) handleBroadcast(ctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) (result model.Envelope, err error) {
+	results := o.callEvery(ctx, method, listOfProviders, userRef, params)
+	succeeded := lo.CountBy(results, func(envelope model.Envelope) bool { return envelope.Status == model.StatusOK })
+	o.log.Infof("Got %d successful responses out of %d providers", succeeded, len(results))
+
+	result = model.Envelope{Status: model.StatusOK, Results: results}
+	if succeeded == 0 {
+		result.Status = model.StatusError
+		return result, nil
+	}
+
+	//Merge results
+	if len(method.MergeRules) > 0 {
+		answers := make([]any, 0, succeeded)
+		for i := range results {
+			if results[i].Status == model.StatusOK {
+				answers = append(answers, results[i].Result)
+			}
+			results[i].Result = nil
+		}
+		merged := mergeResults(method.MergeRules, answers)
+		if err = schema.Validate(method.ResultStructure, merged); err != nil {
+			o.log.Errorf("Merged result of method %s does not match its result structure - %+v", method.Name, err)
+			return result, itserrors.ErrInvalidResult.WithMessage("Merged result does not match the method result structure").WithDetails(err)
+		}
+		result.Result = merged
+		o.log.Infof("Merged %d results with %d rules", len(answers), len(method.MergeRules))
+	}
+	return result, nil
+}
+
+// callEvery calls every provider at once and waits for all of them until the broadcast
+// deadline, returning one envelope per provider in the order of the providers
+func (o *Orquestrator) callEvery(pctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) []model.Envelope {
 	var (
-		resultsChan = make(chan model.Envelope, len(listOfProviders))
-		errorsChan  = make(chan error, len(listOfProviders))
+		ctx, cancel = context.WithTimeout(pctx, o.conf.BroadcastTimeout)
+		results     = make([]model.Envelope, len(listOfProviders))
+		wg          sync.WaitGroup
 	)
+	defer cancel()
 
 	// Call providers
-	for _, provider := range listOfProviders {
-		go o.callProvider(ctx, func() {}, provider, resultsChan, errorsChan, userRef, method.Name, params)
+	for i, provider := range listOfProviders {
+		wg.Add(1)
+		go func(i int, provider model.Provider) {
+			defer wg.Done()
+			results[i] = o.broadcastProvider(ctx, method, provider, userRef, params)
+		}(i, provider)
 	}
 
-	// Wait for the first response
-	select {
-	case result = <-resultsChan:
-		o.log.Infof("Got a response from provider")
-	case err = <-errorsChan:
-		o.log.Errorf("Got an error from provider: %+v", err)
-	}
+	// Wait for every response
+	wg.Wait()
+	return results
+}
+
+// broadcastProvider calls a single provider of a broadcast and never fails, the outcome
+// of the call is reported in the envelope status
+func (o *Orquestrator) broadcastProvider(ctx context.Context, method model.Method, provider model.Provider, userRef string, params []any) (envelope model.Envelope) {
+	var (
+		response *req.Response
+		err      error
+	)
 
+	envelope = model.Envelope{Provider: provider.Name, Status: model.StatusOK}
+	if response, err = o.callProviderMethod(ctx, method, provider, userRef, params); err == nil {
+		envelope.Result, err = o.readResult(method, provider, response)
+	}
+	if err != nil {
+		o.log.Errorf("Got an error from provider %s: %+v", provider.Slug, err)
+		envelope.Status = model.StatusError
+		if errors.Is(err, context.DeadlineExceeded) {
+			envelope.Status = model.StatusTimeout
+		}
+		envelope.Error = asProviderError(provider, err)
+	}
 	return
 }
 
@@ -101,46 +200,232 @@ func (o *Orquestrator) handleConcurrent(pctx context.Context, method model.Metho
 
 	// Call providers
 	for _, provider := range listOfProviders {
-		go o.callProvider(ctx, cancel, provider, resultsChan, errorsChan, userRef, method.Name, params)
+		go o.callProvider(ctx, cancel, method, provider, resultsChan, errorsChan, userRef, params)
 	}
 
-	// Wait for the first response
-	select {
-	case result = <-resultsChan:
-		o.log.Infof("Got a response from provider")
-	case err = <-errorsChan:
-		o.log.Errorf("Got an error from provider: %+v", err)
+	// Wait for the first response, a failing provider only loses the race
+	for range listOfProviders {
+		select {
+		case result = <-resultsChan:
+			o.log.Infof("Got a response from provider")
+			return result, nil
+		case err = <-errorsChan:
+			o.log.Errorf("Got an error from provider: %+v", err)
+		}
 	}
 
-	return
+	return result, itserrors.ErrNoProviderAnswered.WithMessage(fmt.Sprintf("no provider could handle the request, last error: %v", err))
+}
+
+// handleHedged calls the providers one after the other, in selection order, launching
+// the next one when no answer arrived within the hedge delay or when a provider failed.
+// The first answer wins and cancels the calls still running.
+func (o *Orquestrator) handleHedged(pctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) (result model.Envelope, err error) {
+	var (
+		ctx, cancel = context.WithCancel(pctx)
+		resultsChan = make(chan model.Envelope, len(listOfProviders))
+		errorsChan  = make(chan error, len(listOfProviders))
+		delay       = o.hedgeDelay(ctx, method, listOfProviders[0])
+		launched    = 0
+		running     = 0
+	)
+	defer cancel()
+
+	launch := func() {
+		o.log.Infof("Calling provider %s (%d/%d)", listOfProviders[launched].Slug, launched+1, len(listOfProviders))
+		go o.callProvider(ctx, cancel, method, listOfProviders[launched], resultsChan, errorsChan, userRef, params)
+		launched++
+		running++
+	}
+
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for running > 0 {
+		hedge := timer.C
+		if launched == len(listOfProviders) {
+			hedge = nil
+		}
+
+		select {
+		case result = <-resultsChan:
+			o.log.Infof("Got a response after calling %d providers", launched)
+			return result, nil
+		case err = <-errorsChan:
+			o.log.Errorf("Got an error from provider: %+v", err)
+			running--
+			if launched < len(listOfProviders) {
+				launch()
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(delay)
+			}
+		case <-hedge:
+			o.log.Infof("No answer within %s, hedging", delay)
+			launch()
+			timer.Reset(delay)
+		}
+	}
+
+	return result, itserrors.ErrNoProviderAnswered.WithMessage(fmt.Sprintf("no provider could handle the request, last error: %v", err))
+}
+
+// hedgeDelay is the delay set on the method or else the 95th percentile latency of the
+// first provider. Without enough calls to trust that percentile the default delay is used.
+func (o *Orquestrator) hedgeDelay(ctx context.Context, method model.Method, provider model.Provider) time.Duration {
+	const minCalls = 10
+	if method.HedgeDelayMs > 0 {
+		return time.Duration(method.HedgeDelayMs) * time.Millisecond
+	}
+	stats, err := o.stats.Get(ctx, provider.Slug, method)
+	if err != nil {
+		o.log.Errorf("Error loading call stats of provider %s - %+v", provider.Slug, err)
+	}
+	if err != nil || stats.Calls < minCalls || stats.P95Latency == 0 {
+		return o.conf.HedgeDelay
+	}
+	return time.Duration(stats.P95Latency) * time.Millisecond
 }
 
 func (o *Orquestrator) handleIndepotent(ctx context.Context, method model.Method, listOfProviders []model.Provider, userRef string, params []any) (result model.Envelope, err error) {
 	for _, provider := range listOfProviders {
-		var response *req.Response
-		if response, err = provider.CallProviderMethod(ctx, o.conf.HashSecret, userRef, method.Name, params); err != nil {
+		var (
+			response *req.Response
+			value    any
+		)
+		if response, err = o.callProviderMethod(ctx, method, provider, userRef, params); err != nil {
 			o.log.Errorf("Got an error from provider: %+v", err)
 			continue
 		}
+		if value, err = o.readResult(method, provider, response); err != nil {
+			o.log.Errorf("Got an invalid response from provider: %+v", err)
+			continue
+		}
 		return model.Envelope{
 			Provider: provider.Name,
-			Result:   response.SuccessResult(),
+			Result:   value,
 		}, nil
 	}
 
-	return result, errors.New("no provider could handle the request")
+	return result, itserrors.ErrNoProviderAnswered.WithMessage(fmt.Sprintf("no provider could handle the request, last error: %v", err))
 }
 
 // callProvider launch a goroutine for each provider
-func (o *Orquestrator) callProvider(ctx context.Context, closeRun func(), provider model.Provider, resultsChan chan model.Envelope, errorsChan chan error, userRef, methodName string, params []any) {
-	// Here you'll need to replace 'CallProviderMethod' with the actual method that makes the request
-	if response, err := provider.CallProviderMethod(ctx, o.conf.HashSecret, userRef, methodName, params); err != nil {
-		errorsChan <- err
-	} else {
-		resultsChan <- model.Envelope{
-			Provider: provider.Name,
-			Result:   response.SuccessResult(),
-		} // Push the response into the results channel
-		closeRun() // Cancel the context, this will stop other running requests
+func (o *Orquestrator) callProvider(ctx context.Context, closeRun func(), method model.Method, provider model.Provider, resultsChan chan model.Envelope, errorsChan chan error, userRef string, params []any) {
+	var (
+		response *req.Response
+		value    any
+		err      error
+	)
+	if response, err = o.callProviderMethod(ctx, method, provider, userRef, params); err != nil {
+		errorsChan <- asProviderError(provider, err)
+		return
+	}
+	if value, err = o.readResult(method, provider, response); err != nil {
+		errorsChan <- asProviderError(provider, err)
+		return
+	}
+	resultsChan <- model.Envelope{
+		Provider: provider.Name,
+		Result:   value,
+	} // Push the response into the results channel
+	closeRun() // Cancel the context, this will stop other running requests
+}
+
+// callProviderMethod calls a method of the provider through its circuit breaker, bounding
+// each attempt by the call policy timeout and retrying retryable failures
+func (o *Orquestrator) callProviderMethod(ctx context.Context, method model.Method, provider model.Provider, userRef string, params []any) (response *req.Response, err error) {
+	policy := o.callPolicy(ctx, method, provider)
+	for retry := 0; ; retry++ {
+		response, err = o.attempt(ctx, policy, method, provider, func(ctx context.Context) (*req.Response, error) {
+			return provider.CallProviderMethod(ctx, o.keyring, userRef, method.Name, method.Version, params)
+		})
+		if retry >= policy.Retries || ctx.Err() != nil || !retryable(err) {
+			return
+		}
+
+		backoff := policy.Backoff(retry, o.conf.MaxBackoff)
+		o.log.Warnf("Retrying call to provider %s in %s (%d/%d)", provider.Slug, backoff, retry+1, policy.Retries)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
 	}
 }
+
+// attempt runs a single call to the provider bounded by the call policy timeout and
+// records its outcome in the provider call stats. Answers with a non-2xx status are failures.
+func (o *Orquestrator) attempt(pctx context.Context, policy model.CallPolicy, method model.Method, provider model.Provider, call func(context.Context) (*req.Response, error)) (response *req.Response, err error) {
+	var (
+		ctx, cancel = context.WithTimeout(pctx, policy.Timeout(o.conf.ProviderTimeout))
+		start       = time.Now()
+	)
+	defer cancel()
+	defer func() { o.recordCall(pctx, method, provider, start, err) }()
+
+	if response, err = o.withBreaker(ctx, provider, func() (*req.Response, error) { return call(ctx) }); err != nil {
+		return
+	}
+	if !response.IsSuccessState() {
+		return response, providerFailure(provider, response)
+	}
+	return
+}
+
+// recordCall keeps the outcome of a call for the selection strategies. Calls the provider
+// never received, or that the orquestrator cancelled itself, say nothing about it.
+func (o *Orquestrator) recordCall(ctx context.Context, method model.Method, provider model.Provider, start time.Time, err error) {
+	if errors.Is(err, itserrors.ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+		return
+	}
+	o.stats.Record(ctx, provider.Slug, method, model.CallSample{Success: err == nil, Latency: time.Since(start), At: start})
+}
+
+// callPolicy returns the method call policy with the overrides of the provider enrollment
+func (o *Orquestrator) callPolicy(ctx context.Context, method model.Method, provider model.Provider) model.CallPolicy {
+	var enrollment model.MethodProvider
+	if err := o.db.WithContext(ctx).
+		Where("method_id = ? AND provider_id = ?", method.ID, provider.ID).
+		First(&enrollment).Error; err != nil {
+		o.log.Errorf("Error getting enrollment of provider %s, using the method call policy: %+v", provider.Slug, err)
+		return method.CallPolicy
+	}
+	return method.CallPolicy.Override(enrollment.Overrides)
+}
+
+// withBreaker refuses to call a provider whose circuit is open and reports the outcome of
+// the call to the breaker. Calls cancelled by the orquestrator itself, like the losers of
+// a concurrent race, are not counted as failures.
+func (o *Orquestrator) withBreaker(ctx context.Context, provider model.Provider, call func() (*req.Response, error)) (response *req.Response, err error) {
+	if err = o.breaker.Allow(ctx, provider.Slug); err != nil {
+		o.log.Warnf("Circuit of provider %s is open, skipping it", provider.Slug)
+		return nil, fmt.Errorf("%w - %s", err, provider.Slug)
+	}
+
+	response, err = call()
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err != nil, response.StatusCode >= 500:
+		o.breaker.Failure(ctx, provider.Slug)
+	default:
+		o.breaker.Success(ctx, provider.Slug)
+	}
+	return
+}
+
+// readResult reads the JSON body answered by a provider and checks it against the
+// result structure declared by the method
+func (o *Orquestrator) readResult(method model.Method, provider model.Provider, response *req.Response) (result any, err error) {
+	if err = response.Unmarshal(&result); err != nil {
+		return
+	}
+	if err = schema.Validate(method.ResultStructure, result); err != nil {
+		o.log.Warnf("Provider %s answered %s with a non-conforming result: %+v", provider.Slug, method.Name, err)
+		failure := itserrors.ErrInvalidResult.WithDetails(err)
+		failure.Provider = provider.Slug
+		return nil, failure
+	}
+	return
+}
@@ -2,10 +2,8 @@ package service
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/caioeverest/fed-its/adapter/database"
 	"github.com/caioeverest/fed-its/adapter/redis"
@@ -16,6 +14,8 @@ import (
 	"github.com/caioeverest/fed-its/internal/validate"
 	"github.com/caioeverest/fed-its/model"
 	"github.com/samber/lo"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
 )
 
 const hide = "***********"
@@ -23,9 +23,16 @@ const hide = "***********"
 type ProviderI interface {
 	Create(ctx context.Context, provider model.Provider) (model.Provider, error)
 	Get(ctx context.Context, slug string) (model.Provider, error)
-	Update(ctx context.Context, signature, slug string, provider model.Provider) (model.Provider, error)
-	Delete(ctx context.Context, signature, slug string) error
+	Update(ctx context.Context, signed SignedRequest, slug string, provider model.Provider) (model.Provider, error)
+	Delete(ctx context.Context, signed SignedRequest, slug string) error
 	List(ctx context.Context, method string) ([]model.Provider, error)
+	Enroll(ctx context.Context, signed SignedRequest, slug, method, version string, options model.Enrollment) (model.MethodProvider, error)
+	Unenroll(ctx context.Context, signed SignedRequest, slug, method, version string) error
+	Methods(ctx context.Context, slug string) ([]model.Method, error)
+	RotateSecret(ctx context.Context, signed SignedRequest, slug string) (model.SecretRotation, error)
+	Approve(ctx context.Context, slug string) (model.Provider, error)
+	Reject(ctx context.Context, slug string) (model.Provider, error)
+	RotateSecrets(ctx context.Context) (int, error)
 }
 
 type Proveder struct {
@@ -34,10 +41,22 @@ type Proveder struct {
 	db       *database.Database
 	validate *validate.Validate
 	redis    *redis.Client
+	keyring  *aes.Keyring
 }
 
-func NewProvider(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate, redis *redis.Client) ProviderI {
-	return &Proveder{cfg, log, db, validate, redis}
+func NewProvider(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate, redis *redis.Client, keyring *aes.Keyring) ProviderI {
+	return &Proveder{cfg, log, db, validate, redis, keyring}
+}
+
+// RotateSecrets re-encrypts, when the application starts, every provider secret that is
+// not yet under the active encryption key
+func RotateSecrets(lc fx.Lifecycle, providers ProviderI) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) (err error) {
+			_, err = providers.RotateSecrets(ctx)
+			return
+		},
+	})
 }
 
 // Create godoc
@@ -53,6 +72,9 @@ func (p *Proveder) Create(ctx context.Context, provider model.Provider) (result
 		return
 	}
 
+	//New providers receive no traffic until an admin approves them
+	provider.Status = model.ProviderPending
+
 	//Encrypt secret
 	p.log.Info("Encrypting provider secret")
 	if err = p.encrypt(ctx, &provider); err != nil {
@@ -88,7 +110,7 @@ func (p *Proveder) Get(ctx context.Context, slug string) (provider model.Provide
 // Update godoc
 // @Summary Update a provider
 // @Description Update a provider by slug name and return it
-func (p *Proveder) Update(ctx context.Context, signature, slug string, update model.Provider) (provider model.Provider, err error) {
+func (p *Proveder) Update(ctx context.Context, signed SignedRequest, slug string, update model.Provider) (provider model.Provider, err error) {
 	p.log.Infof("Update provider %s requested", slug)
 
 	//Search for provider
@@ -99,11 +121,23 @@ func (p *Proveder) Update(ctx context.Context, signature, slug string, update mo
 	}
 
 	//Check signature
-	if !p.checkSignature(ctx, provider, signature, provider) {
+	if !p.checkSignature(ctx, provider, signed) {
 		p.log.Errorf("Signature check failed")
 		return model.Provider{}, itserrors.ErrInvalidSignature
 	}
 
+	//Only an admin changes the status of a provider
+	update.Status = ""
+
+	//Encrypt new secret
+	if update.Secret != "" {
+		p.log.Info("Encrypting provider secret")
+		if err = p.encrypt(ctx, &update); err != nil {
+			p.log.Errorf("Error encrypting provider secret - %+v", err)
+			return
+		}
+	}
+
 	//Update provider
 	if err = p.db.WithContext(ctx).Model(&provider).Updates(update).Error; err != nil {
 		p.log.Errorf("Error updating provider - %+v", err)
@@ -117,7 +151,7 @@ func (p *Proveder) Update(ctx context.Context, signature, slug string, update mo
 // Delete godoc
 // @Summary Delete a provider
 // @Description Delete a provider by slug name
-func (p *Proveder) Delete(ctx context.Context, signature, slug string) (err error) {
+func (p *Proveder) Delete(ctx context.Context, signed SignedRequest, slug string) (err error) {
 	var (
 		provider model.Provider
 	)
@@ -131,7 +165,7 @@ func (p *Proveder) Delete(ctx context.Context, signature, slug string) (err erro
 	}
 
 	//Check signature
-	if !p.checkSignature(ctx, provider, signature, provider) {
+	if !p.checkSignature(ctx, provider, signed) {
 		p.log.Errorf("Signature check failed")
 		return itserrors.ErrInvalidSignature
 	}
@@ -147,15 +181,20 @@ func (p *Proveder) Delete(ctx context.Context, signature, slug string) (err erro
 
 // List godoc
 // @Summary List providers
-// @Description List providers that implement a method
+// @Description List providers that implement any version of a method, each one once
 func (p *Proveder) List(ctx context.Context, method string) (list []model.Provider, err error) {
 	p.log.Infof("List providers that implement method %s", method)
 
 	//List providers
+	enrollments := p.db.WithContext(ctx).
+		Table("method_providers").
+		Select("1").
+		Joins("JOIN methods ON methods.id = method_providers.method_id").
+		Where("method_providers.provider_id = providers.id").
+		Where("methods.name = ? AND methods.deleted_at IS NULL", method)
 	if err = p.db.WithContext(ctx).
-		Joins("JOIN provider_methods ON provider_methods.provider_id = providers.id").
-		Joins("JOIN methods ON methods.id = provider_methods.method_id").
-		Where("methods.name = ?", method).
+		Where("EXISTS (?)", enrollments).
+		Scopes(approved).
 		Find(&list).Error; err != nil {
 		p.log.Errorf("Error listing providers - %+v", err)
 		return
@@ -166,35 +205,313 @@ func (p *Proveder) List(ctx context.Context, method string) (list []model.Provid
 	return
 }
 
-func (p *Proveder) checkSignature(ctx context.Context, model model.Provider, signature string, content any) bool {
+// Enroll godoc
+// @Summary Enroll a provider in a method
+// @Description Enroll a provider in the newest version of a method matching the version constraint, the latest
+// @Description stable version by default, so the orquestrator can route calls of that version to it, optionally
+// @Description overriding the method call policy and setting the weight, priority and cost of this provider
+func (p *Proveder) Enroll(ctx context.Context, signed SignedRequest, slug, methodName, constraint string, options model.Enrollment) (enrollment model.MethodProvider, err error) {
 	var (
-		contentBytes []byte
-		secret       string
-		err          error
+		provider model.Provider
+		method   model.Method
 	)
-	p.log.Infof("Checking signature for provider %s", model.Slug)
-	if secret, err = p.decrypt(ctx, model); err != nil {
-		p.log.Errorf("Error decrypting provider secret - %+v", err)
-		return false
+	p.log.Infof("Enroll provider %s in method %s requested", slug, methodName)
+
+	//Validate input
+	if err = p.validate.Struct(options); err != nil {
+		p.log.Errorf("Validation error: %+v", err)
+		return
+	}
+
+	//Search for provider
+	p.log.Infof("Searching for provider %s", slug)
+	if err = p.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		p.log.Errorf("Error getting provider - %+v", err)
+		return
 	}
-	if contentBytes, err = json.Marshal(content); err != nil {
-		p.log.Errorf("Error marshaling content - %+v", err)
-		return false
+
+	//Check signature
+	if !p.checkSignature(ctx, provider, signed) {
+		p.log.Errorf("Signature check failed")
+		return model.MethodProvider{}, itserrors.ErrInvalidSignature
 	}
 
-	h := hmac.New(sha256.New, []byte(secret))
-	h.Write([]byte(contentBytes))
-	sha := hex.EncodeToString(h.Sum(nil))
+	//Search for method
+	p.log.Infof("Searching for method %s", methodName)
+	if method, err = findMethod(ctx, p.db, methodName, constraint); err != nil {
+		p.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	//Enroll provider
+	enrollment = model.MethodProvider{MethodID: method.ID, ProviderID: provider.ID}
+	if err = p.db.WithContext(ctx).
+		Where(&enrollment).
+		FirstOrCreate(&enrollment).Error; err != nil {
+		p.log.Errorf("Error enrolling provider - %+v", err)
+		return
+	}
+	if err = p.db.WithContext(ctx).
+		Model(&model.MethodProvider{}).
+		Where("method_id = ? AND provider_id = ?", method.ID, provider.ID).
+		Updates(map[string]any{
+			"override_timeout_ms":     options.TimeoutMs,
+			"override_retries":        options.Retries,
+			"override_backoff_ms":     options.BackoffMs,
+			"override_backoff_policy": options.BackoffPolicy,
+			"weight":                  lo.Ternary(options.Weight == 0, 1, options.Weight),
+			"priority":                options.Priority,
+			"cost":                    options.Cost,
+		}).Error; err != nil {
+		p.log.Errorf("Error updating enrollment call policy - %+v", err)
+		return
+	}
+	enrollment.Method = method
+	enrollment.Overrides = options.CallPolicyOverride
+	enrollment.Weight = lo.Ternary(options.Weight == 0, 1, options.Weight)
+	enrollment.Priority, enrollment.Cost = options.Priority, options.Cost
 
-	return sha == signature
+	p.log.Infof("Provider %s enrolled in method %s version %s", slug, methodName, method.Version)
+	return enrollment, nil
 }
 
-func (p *Proveder) encrypt(ctx context.Context, model *model.Provider) (err error) {
-	model.Secret, err = aes.Encrypt(p.cfg.HashSecret, model.Secret)
+// Unenroll godoc
+// @Summary Unenroll a provider from a method
+// @Description Remove a provider from the list of providers that implement the newest version of a method matching
+// @Description the version constraint, the latest stable version by default
+func (p *Proveder) Unenroll(ctx context.Context, signed SignedRequest, slug, methodName, constraint string) (err error) {
+	var (
+		provider model.Provider
+		method   model.Method
+	)
+	p.log.Infof("Unenroll provider %s from method %s requested", slug, methodName)
+
+	//Search for provider
+	p.log.Infof("Searching for provider %s", slug)
+	if err = p.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		p.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+
+	//Check signature
+	if !p.checkSignature(ctx, provider, signed) {
+		p.log.Errorf("Signature check failed")
+		return itserrors.ErrInvalidSignature
+	}
+
+	//Search for method
+	p.log.Infof("Searching for method %s", methodName)
+	if method, err = findMethod(ctx, p.db, methodName, constraint); err != nil {
+		p.log.Errorf("Error getting method - %+v", err)
+		return
+	}
+
+	//Unenroll provider
+	result := p.db.WithContext(ctx).
+		Where("method_id = ? AND provider_id = ?", method.ID, provider.ID).
+		Delete(&model.MethodProvider{})
+	if err = result.Error; err != nil {
+		p.log.Errorf("Error unenrolling provider - %+v", err)
+		return
+	}
+	if result.RowsAffected == 0 {
+		p.log.Errorf("Provider %s is not enrolled in method %s version %s", slug, methodName, method.Version)
+		return itserrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// Methods godoc
+// @Summary List provider methods
+// @Description List the methods a provider is enrolled in
+func (p *Proveder) Methods(ctx context.Context, slug string) (list []model.Method, err error) {
+	var (
+		provider model.Provider
+	)
+	p.log.Infof("List methods of provider %s", slug)
+
+	//Search for provider
+	if err = p.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		p.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+
+	//List methods
+	if err = p.db.WithContext(ctx).
+		Joins("JOIN method_providers ON method_providers.method_id = methods.id").
+		Where("method_providers.provider_id = ?", provider.ID).
+		Find(&list).Error; err != nil {
+		p.log.Errorf("Error listing methods - %+v", err)
+		return
+	}
+
+	p.log.Infof("Provider %s is enrolled in %d methods", slug, len(list))
 	return
 }
 
-func (p *Proveder) decrypt(ctx context.Context, model model.Provider) (secret string, err error) {
-	secret, err = aes.Decrypt(p.cfg.HashSecret, model.Secret)
+// RotateSecret godoc
+// @Summary Rotate the secret of a provider
+// @Description Replace the secret of a provider by a generated one, keeping the previous secret
+// @Description valid for inbound and outbound signatures during the grace period
+func (p *Proveder) RotateSecret(ctx context.Context, signed SignedRequest, slug string) (rotation model.SecretRotation, err error) {
+	var (
+		provider  model.Provider
+		secret    string
+		encrypted string
+	)
+	p.log.Infof("Secret rotation of provider %s requested", slug)
+
+	//Search for provider
+	p.log.Infof("Searching for provider %s", slug)
+	if err = p.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		p.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+
+	//Check signature
+	if !p.checkSignature(ctx, provider, signed) {
+		p.log.Errorf("Signature check failed")
+		return model.SecretRotation{}, itserrors.ErrInvalidSignature
+	}
+
+	//Generate new secret
+	if secret, err = newSecret(); err != nil {
+		p.log.Errorf("Error generating provider secret - %+v", err)
+		return
+	}
+	if encrypted, err = p.keyring.Encrypt(secret); err != nil {
+		p.log.Errorf("Error encrypting provider secret - %+v", err)
+		return
+	}
+
+	//Replace secret, the current one becomes the previous
+	validUntil := time.Now().Add(p.cfg.SecretGracePeriod).UTC()
+	result := p.db.WithContext(ctx).
+		Model(&model.Provider{}).
+		Where("id = ? AND secret = ?", provider.ID, provider.Secret).
+		Updates(map[string]any{
+			"secret":                     encrypted,
+			"previous_secret":            provider.Secret,
+			"previous_secret_expires_at": validUntil,
+		})
+	if err = result.Error; err != nil {
+		p.log.Errorf("Error rotating provider secret - %+v", err)
+		return
+	}
+	if result.RowsAffected == 0 {
+		p.log.Errorf("Secret of provider %s was changed during the rotation", slug)
+		return model.SecretRotation{}, itserrors.ErrConflict
+	}
+
+	p.log.Infof("Secret of provider %s rotated, previous secret valid until %s", slug, validUntil)
+	return model.SecretRotation{Secret: secret, PreviousValidUntil: validUntil}, nil
+}
+
+// Approve godoc
+// @Summary Approve a provider
+// @Description Allow a pending provider to receive user traffic
+func (p *Proveder) Approve(ctx context.Context, slug string) (model.Provider, error) {
+	return p.review(ctx, slug, model.ProviderApproved)
+}
+
+// Reject godoc
+// @Summary Reject a provider
+// @Description Stop a provider from receiving user traffic
+func (p *Proveder) Reject(ctx context.Context, slug string) (model.Provider, error) {
+	return p.review(ctx, slug, model.ProviderRejected)
+}
+
+// review sets the status an admin gave to a provider
+func (p *Proveder) review(ctx context.Context, slug string, status model.ProviderStatus) (provider model.Provider, err error) {
+	p.log.Infof("Provider %s requested to be %s", slug, status)
+
+	//Search for provider
+	if err = p.db.WithContext(ctx).Where("slug = ?", slug).First(&provider).Error; err != nil {
+		p.log.Errorf("Error getting provider - %+v", err)
+		return
+	}
+
+	//Update status
+	if err = p.db.WithContext(ctx).Model(&provider).Update("status", status).Error; err != nil {
+		p.log.Errorf("Error updating provider status - %+v", err)
+		return
+	}
+	provider.Secret = hide
+
+	p.log.Infof("Provider %s is %s", slug, status)
+	return provider, nil
+}
+
+// RotateSecrets godoc
+// @Summary Rotate provider secrets
+// @Description Re-encrypt every provider secret that is not under the active encryption key
+func (p *Proveder) RotateSecrets(ctx context.Context) (rotated int, err error) {
+	var providers []model.Provider
+	p.log.Infof("Rotating provider secrets to encryption key %s", p.keyring.Active())
+
+	if err = p.db.WithContext(ctx).Find(&providers).Error; err != nil {
+		p.log.Errorf("Error listing providers - %+v", err)
+		return
+	}
+
+	for _, provider := range providers {
+		var count int64
+		if count, err = p.reencrypt(ctx, provider, "secret", provider.Secret); err != nil {
+			return
+		}
+		rotated += int(count)
+
+		// A previous secret is still accepted during its grace period
+		if provider.PreviousSecret == "" {
+			continue
+		}
+		if _, err = p.reencrypt(ctx, provider, "previous_secret", provider.PreviousSecret); err != nil {
+			return
+		}
+	}
+
+	p.log.Infof("Rotated %d provider secrets", rotated)
+	return rotated, nil
+}
+
+// reencrypt moves a secret column of the provider to the active encryption key
+func (p *Proveder) reencrypt(ctx context.Context, provider model.Provider, column, ciphertext string) (rotated int64, err error) {
+	var (
+		secret    string
+		encrypted string
+	)
+	if !p.keyring.NeedsRotation(ciphertext) {
+		return
+	}
+	if secret, err = p.keyring.Decrypt(ciphertext); err != nil {
+		// Keep rotating the others, this secret must be replaced by its provider
+		p.log.Errorf("Error decrypting %s of provider %s, it must be set again - %+v", column, provider.Slug, err)
+		return 0, nil
+	}
+	if encrypted, err = p.keyring.Encrypt(secret); err != nil {
+		p.log.Errorf("Error encrypting %s of provider %s - %+v", column, provider.Slug, err)
+		return
+	}
+
+	// Only replace the secret that was read, another instance may be rotating it too
+	result := p.db.WithContext(ctx).
+		Model(&model.Provider{}).
+		Where(fmt.Sprintf("id = ? AND %s = ?", column), provider.ID, ciphertext).
+		Update(column, encrypted)
+	if err = result.Error; err != nil {
+		p.log.Errorf("Error updating %s of provider %s - %+v", column, provider.Slug, err)
+		return
+	}
+	return result.RowsAffected, nil
+}
+
+func (p *Proveder) encrypt(ctx context.Context, model *model.Provider) (err error) {
+	model.Secret, err = p.keyring.Encrypt(model.Secret)
 	return
 }
+
+// approved is a scope that keeps only the providers an admin approved
+func approved(db *gorm.DB) *gorm.DB {
+	return db.Where("providers.status = ?", model.ProviderApproved)
+}
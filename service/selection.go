@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/samber/lo"
+)
+
+// Candidate is a provider the orquestrator may call, with what strategies order it by
+type Candidate struct {
+	Provider   model.Provider
+	Enrollment model.MethodProvider
+	Stats      model.CallStats
+	Health     model.ProviderHealth
+}
+
+// Strategy orders the candidates of a call, the orquestrator calls them in that order
+type Strategy interface {
+	Order(ctx context.Context, method model.Method, candidates []Candidate) []Candidate
+}
+
+// StrategyFunc adapts a function to the Strategy interface
+type StrategyFunc func(ctx context.Context, method model.Method, candidates []Candidate) []Candidate
+
+func (f StrategyFunc) Order(ctx context.Context, method model.Method, candidates []Candidate) []Candidate {
+	return f(ctx, method, candidates)
+}
+
+type SelectorI interface {
+	Select(ctx context.Context, method model.Method, candidates []Candidate) []model.Provider
+}
+
+type Selector struct {
+	cfg        *config.Config
+	log        *logger.Logger
+	db         *database.Database
+	redis      *redis.Client
+	stats      StatsI
+	strategies map[model.SelectionStrategy]Strategy
+}
+
+func NewSelector(cfg *config.Config, log *logger.Logger, db *database.Database, redis *redis.Client, stats StatsI) SelectorI {
+	s := &Selector{cfg: cfg, log: log, db: db, redis: redis, stats: stats}
+	s.strategies = map[model.SelectionStrategy]Strategy{
+		model.SelectionOrdered:       StrategyFunc(ordered),
+		model.SelectionRoundRobin:    StrategyFunc(s.roundRobin),
+		model.SelectionWeighted:      StrategyFunc(weighted),
+		model.SelectionLowestLatency: StrategyFunc(lowestLatency),
+		model.SelectionCheapest:      StrategyFunc(cheapest),
+		model.SelectionPriority:      StrategyFunc(priority),
+		model.SelectionRandom:        StrategyFunc(random),
+	}
+	return s
+}
+
+// Select orders the candidates, with the health the health service loaded, by the
+// selection strategy of the method and keeps, when the method sets a selection limit,
+// only the first ones. Whatever the strategy degraded providers stay after the healthy
+// ones. If the enrollments cannot be read the candidates are kept in the order they came.
+func (s *Selector) Select(ctx context.Context, method model.Method, candidates []Candidate) []model.Provider {
+	var (
+		enrollments []model.MethodProvider
+		sorted      = candidates
+		strategy    = s.strategies[method.Selection]
+	)
+	if strategy == nil {
+		strategy = StrategyFunc(ordered)
+	}
+
+	if err := s.db.WithContext(ctx).
+		Where("method_id = ? AND provider_id IN ?", method.ID, lo.Map(candidates, func(candidate Candidate, _ int) uint { return candidate.Provider.ID })).
+		Find(&enrollments).Error; err != nil {
+		s.log.Errorf("Error loading enrollments of method %s - %+v", method.Name, err)
+	} else {
+		byProvider := lo.KeyBy(enrollments, func(enrollment model.MethodProvider) uint { return enrollment.ProviderID })
+		for i, candidate := range candidates {
+			stats, err := s.stats.Get(ctx, candidate.Provider.Slug, method)
+			if err != nil {
+				s.log.Errorf("Error loading call stats of provider %s - %+v", candidate.Provider.Slug, err)
+			}
+			candidates[i].Enrollment, candidates[i].Stats = byProvider[candidate.Provider.ID], stats
+		}
+		sorted = strategy.Order(ctx, method, candidates)
+		s.log.Infof("Selected providers of method %s with the %s strategy", method.Name, method.Selection)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return !degraded(sorted[i]) && degraded(sorted[j]) })
+	return limit(method, lo.Map(sorted, func(candidate Candidate, _ int) model.Provider { return candidate.Provider }))
+}
+
+func limit(method model.Method, providers []model.Provider) []model.Provider {
+	if method.SelectionLimit > 0 && len(providers) > method.SelectionLimit {
+		return providers[:method.SelectionLimit]
+	}
+	return providers
+}
+
+func degraded(candidate Candidate) bool {
+	return candidate.Health.Status == model.HealthDegraded
+}
+
+func ordered(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	return candidates
+}
+
+// roundRobin rotates the candidates by a counter shared by every instance through redis
+func (s *Selector) roundRobin(ctx context.Context, method model.Method, candidates []Candidate) []Candidate {
+	if len(candidates) == 0 {
+		return candidates
+	}
+	turn, err := s.redis.Incr(ctx, fmt.Sprintf("selection:%s:%s", method.Name, method.Version)).Result()
+	if err != nil {
+		s.log.Errorf("Error incrementing round robin of method %s - %+v", method.Name, err)
+		return candidates
+	}
+	start := int(turn % int64(len(candidates)))
+	return append(candidates[start:], candidates[:start]...)
+}
+
+// weighted draws the candidates one by one, each with a chance proportional to its weight
+func weighted(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	var (
+		remaining = append([]Candidate{}, candidates...)
+		result    = make([]Candidate, 0, len(candidates))
+	)
+	for len(remaining) > 0 {
+		total := lo.SumBy(remaining, func(candidate Candidate) int { return weight(candidate) })
+		draw, picked := rand.Intn(total), 0
+		for i, candidate := range remaining {
+			if draw -= weight(candidate); draw < 0 {
+				picked = i
+				break
+			}
+		}
+		result = append(result, remaining[picked])
+		remaining = append(remaining[:picked], remaining[picked+1:]...)
+	}
+	return result
+}
+
+func weight(candidate Candidate) int {
+	return lo.Max([]int{candidate.Enrollment.Weight, 1})
+}
+
+// lowestLatency sorts the candidates by average latency divided by success rate, so a
+// fast provider that often fails does not come first. Candidates without calls come
+// first, so they get the chance to be measured.
+func lowestLatency(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(i, j int) bool { return expectedLatency(candidates[i]) < expectedLatency(candidates[j]) })
+	return candidates
+}
+
+func expectedLatency(candidate Candidate) float64 {
+	if candidate.Stats.Calls == 0 {
+		return 0
+	}
+	return float64(candidate.Stats.AvgLatency+1) / lo.Max([]float64{candidate.Stats.SuccessRate, 0.01})
+}
+
+// cheapest sorts the candidates by enrollment cost, the most reliable first on a tie
+func cheapest(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Enrollment.Cost != candidates[j].Enrollment.Cost {
+			return candidates[i].Enrollment.Cost < candidates[j].Enrollment.Cost
+		}
+		return candidates[i].Stats.SuccessRate > candidates[j].Stats.SuccessRate
+	})
+	return candidates
+}
+
+func priority(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Enrollment.Priority < candidates[j].Enrollment.Priority })
+	return candidates
+}
+
+func random(_ context.Context, _ model.Method, candidates []Candidate) []Candidate {
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	return candidates
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/caioeverest/fed-its/model"
+	"github.com/caioeverest/fed-its/signature"
+)
+
+// SignedRequest is a management call a provider signed with its secret
+type SignedRequest struct {
+	Header string
+	Method string
+	// URI is the path of the request with its query string
+	URI  string
+	Body []byte
+}
+
+// checkSignature verifies that the provider signed the method, URI, body and timestamp
+// of the request, with any of its valid secrets, and that the request was not received before
+func (p *Proveder) checkSignature(ctx context.Context, model model.Provider, signed SignedRequest) bool {
+	var (
+		secrets []string
+		request signature.Signature
+		err     error
+	)
+	p.log.Infof("Checking signature for provider %s", model.Slug)
+	if secrets, err = model.Secrets(p.keyring); err != nil {
+		p.log.Errorf("Error decrypting provider secrets - %+v", err)
+		return false
+	}
+
+	if request, err = signature.Verify(signed.Header, signature.Request(signed.Method, signed.URI), signed.Body, p.cfg.SignatureTolerance, secrets...); err != nil {
+		p.log.Errorf("Signature of provider %s rejected - %+v", model.Slug, err)
+		return false
+	}
+
+	// A nonce outlives the tolerance window, so a replay is caught until it would be expired anyway
+	fresh, err := p.redis.SetNX(ctx, nonceKey(model.Slug, request.ID), 1, 2*p.cfg.SignatureTolerance).Result()
+	if err != nil {
+		p.log.Errorf("Error storing signature nonce - %+v", err)
+		return false
+	}
+	if !fresh {
+		p.log.Errorf("Signature of provider %s rejected - %+v", model.Slug, signature.ErrReplayed)
+		return false
+	}
+	return true
+}
+
+func nonceKey(slug, id string) string {
+	return fmt.Sprintf("nonce:%s:%s", slug, id)
+}
+
+// newSecret generates a random provider secret
+func newSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
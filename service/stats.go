@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/caioeverest/fed-its/adapter/redis"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/samber/lo"
+)
+
+type StatsI interface {
+	Record(ctx context.Context, slug string, method model.Method, sample model.CallSample)
+	Get(ctx context.Context, slug string, method model.Method) (model.CallStats, error)
+}
+
+type Stats struct {
+	cfg   *config.Config
+	log   *logger.Logger
+	redis *redis.Client
+}
+
+func NewStats(cfg *config.Config, log *logger.Logger, redis *redis.Client) StatsI {
+	return &Stats{cfg, log, redis}
+}
+
+// Record keeps the outcome of a call in the rolling window of the provider for the version
+// of the method. A failure is only logged, losing a sample never fails a call.
+func (s *Stats) Record(ctx context.Context, slug string, method model.Method, sample model.CallSample) {
+	bytes, err := json.Marshal(sample)
+	if err != nil {
+		s.log.Errorf("Error encoding call sample of provider %s - %+v", slug, err)
+		return
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.LPush(ctx, statsKey(slug, method), bytes)
+	pipe.LTrim(ctx, statsKey(slug, method), 0, int64(s.cfg.StatsWindow-1))
+	if _, err = pipe.Exec(ctx); err != nil {
+		s.log.Errorf("Error recording call sample of provider %s - %+v", slug, err)
+	}
+}
+
+// Get summarizes the latest calls of the version of the method to the provider
+func (s *Stats) Get(ctx context.Context, slug string, method model.Method) (stats model.CallStats, err error) {
+	var (
+		raw     []string
+		samples []model.CallSample
+	)
+	stats = model.CallStats{Provider: slug, Method: method.Name, Version: method.Version}
+	if raw, err = s.redis.LRange(ctx, statsKey(slug, method), 0, -1).Result(); err != nil {
+		return
+	}
+	for _, item := range raw {
+		var sample model.CallSample
+		if err = json.Unmarshal([]byte(item), &sample); err != nil {
+			return
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	succeeded := lo.Filter(samples, func(sample model.CallSample, _ int) bool { return sample.Success })
+	stats.Calls = len(samples)
+	stats.SuccessRate = float64(len(succeeded)) / float64(len(samples))
+	if len(succeeded) > 0 {
+		latencies := lo.Map(succeeded, func(sample model.CallSample, _ int) time.Duration { return sample.Latency })
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.AvgLatency = (lo.Sum(latencies) / time.Duration(len(latencies))).Milliseconds()
+		stats.P95Latency = latencies[int(math.Ceil(0.95*float64(len(latencies))))-1].Milliseconds()
+	}
+	return
+}
+
+func statsKey(slug string, method model.Method) string {
+	return fmt.Sprintf("stats:%s:%s:%s", method.Name, method.Version, slug)
+}
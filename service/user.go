@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/internal/config"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/logger"
+	"github.com/caioeverest/fed-its/internal/validate"
+	"github.com/caioeverest/fed-its/model"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+const apiKeyPrefix = "fedits_"
+
+// placeholderAdminAPIKey is the example admin key once shipped in env.example, it is
+// refused since anyone may know it
+const placeholderAdminAPIKey = "fedits_changemeadminapikey"
+
+// ErrPlaceholderAdminAPIKey is returned when ADMIN_API_KEY is still the example key
+var ErrPlaceholderAdminAPIKey = errors.New("ADMIN_API_KEY is the example key, set it to a long random key")
+
+type UserI interface {
+	Register(ctx context.Context, user model.User) (model.UserCredentials, error)
+	Authenticate(ctx context.Context, apiKey string) (model.User, error)
+	Get(ctx context.Context, ref string) (model.User, error)
+	AssignRole(ctx context.Context, ref string, role model.Role) (model.User, error)
+	BootstrapAdmin(ctx context.Context) error
+}
+
+type User struct {
+	cfg      *config.Config
+	log      *logger.Logger
+	db       *database.Database
+	validate *validate.Validate
+}
+
+func NewUser(cfg *config.Config, log *logger.Logger, db *database.Database, validate *validate.Validate) UserI {
+	return &User{cfg, log, db, validate}
+}
+
+// BootstrapAdmin creates, when the application starts, the federation admin configured
+// by ADMIN_API_KEY
+func BootstrapAdmin(lc fx.Lifecycle, users UserI) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return users.BootstrapAdmin(ctx)
+		},
+	})
+}
+
+// Register godoc
+// @Summary Register a new user
+// @Description Register a new user and return the API key it must use to call methods
+func (u *User) Register(ctx context.Context, user model.User) (credentials model.UserCredentials, err error) {
+	var apiKey string
+	u.log.Info("New user requested to be registered")
+
+	//Validate input
+	u.log.Info("Validating user")
+	if err = u.validate.Struct(user); err != nil {
+		u.log.Errorf("Validation error: %+v", err)
+		return
+	}
+
+	//Generate reference and API key
+	if user.Ref, err = newToken(16); err != nil {
+		u.log.Errorf("Error generating user reference - %+v", err)
+		return
+	}
+	if apiKey, err = newToken(32); err != nil {
+		u.log.Errorf("Error generating user API key - %+v", err)
+		return
+	}
+	apiKey = apiKeyPrefix + apiKey
+	user.APIKeyHash = hashToken(apiKey)
+
+	//Everyone registers as consumer, other roles are assigned by an admin
+	user.Role = model.RoleConsumer
+
+	//Create user
+	if err = u.db.WithContext(ctx).Create(&user).Error; err != nil {
+		u.log.Errorf("Error creating user - %+v", err)
+		return
+	}
+
+	u.log.Infof("User %s registered", user.Ref)
+	return model.UserCredentials{User: user, APIKey: apiKey}, nil
+}
+
+// Authenticate godoc
+// @Summary Authenticate a user
+// @Description Find the user that owns an API key
+func (u *User) Authenticate(ctx context.Context, apiKey string) (user model.User, err error) {
+	if apiKey == "" {
+		return user, itserrors.ErrUnauthorized
+	}
+	if err = u.db.WithContext(ctx).Where("api_key_hash = ?", hashToken(apiKey)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = itserrors.ErrUnauthorized
+		}
+		u.log.Errorf("Error authenticating user - %+v", err)
+		return
+	}
+	return
+}
+
+// Get godoc
+// @Summary Get a user
+// @Description Get a user by its reference
+func (u *User) Get(ctx context.Context, ref string) (user model.User, err error) {
+	u.log.Infof("Get user %s requested", ref)
+	if err = u.db.WithContext(ctx).Where("ref = ?", ref).First(&user).Error; err != nil {
+		u.log.Errorf("Error getting user - %+v", err)
+		return
+	}
+	return
+}
+
+// AssignRole godoc
+// @Summary Assign a role to a user
+// @Description Change the role of a user, only a federation admin may do it
+func (u *User) AssignRole(ctx context.Context, ref string, role model.Role) (user model.User, err error) {
+	u.log.Infof("Role %s requested for user %s", role, ref)
+
+	//Validate input
+	if err = u.validate.Struct(model.RoleAssignment{Role: role}); err != nil {
+		u.log.Errorf("Validation error: %+v", err)
+		return
+	}
+
+	//Search for user
+	if err = u.db.WithContext(ctx).Where("ref = ?", ref).First(&user).Error; err != nil {
+		u.log.Errorf("Error getting user - %+v", err)
+		return
+	}
+
+	//Assign role
+	if err = u.db.WithContext(ctx).Model(&user).Update("role", role).Error; err != nil {
+		u.log.Errorf("Error assigning role - %+v", err)
+		return
+	}
+
+	u.log.Infof("User %s is now %s", ref, role)
+	return user, nil
+}
+
+// BootstrapAdmin godoc
+// @Summary Bootstrap the federation admin
+// @Description Create the configured federation admin, or give back its role, so the first roles can be assigned.
+// @Description An account registered with the admin email is taken over by the configured API key.
+func (u *User) BootstrapAdmin(ctx context.Context) (err error) {
+	var (
+		admin = u.cfg.Admin
+		hash  = hashToken(admin.APIKey)
+		user  model.User
+	)
+	switch admin.APIKey {
+	case "":
+		u.log.Warn("ADMIN_API_KEY is empty, no federation admin bootstrapped")
+		return
+	case placeholderAdminAPIKey:
+		u.log.Errorf("Refusing to bootstrap federation admin - %+v", ErrPlaceholderAdminAPIKey)
+		return ErrPlaceholderAdminAPIKey
+	}
+	u.log.Info("Bootstrapping federation admin")
+
+	//Search for admin, by API key or else by email
+	err = u.db.WithContext(ctx).Where("api_key_hash = ?", hash).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = u.db.WithContext(ctx).Where("email = ?", admin.Email).First(&user).Error
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if user.Ref, err = newToken(16); err != nil {
+			u.log.Errorf("Error generating user reference - %+v", err)
+			return
+		}
+		user = model.User{Ref: user.Ref, Name: admin.Name, Email: admin.Email, APIKeyHash: hash, Role: model.RoleFederationAdmin}
+		if err = u.db.WithContext(ctx).Create(&user).Error; err != nil {
+			u.log.Errorf("Error creating federation admin - %+v", err)
+			return
+		}
+	case err != nil:
+		u.log.Errorf("Error getting federation admin - %+v", err)
+		return
+	case user.APIKeyHash != hash || user.Role != model.RoleFederationAdmin:
+		//Whoever registered the admin email loses access, only the configured key is admin
+		if err = u.db.WithContext(ctx).Model(&user).Updates(model.User{APIKeyHash: hash, Role: model.RoleFederationAdmin}).Error; err != nil {
+			u.log.Errorf("Error assigning federation admin role - %+v", err)
+			return
+		}
+	}
+
+	u.log.Infof("Federation admin is user %s", user.Ref)
+	return
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caioeverest/fed-its/adapter/database"
+	"github.com/caioeverest/fed-its/internal/itserrors"
+	"github.com/caioeverest/fed-its/internal/version"
+	"github.com/caioeverest/fed-its/model"
+	"github.com/samber/lo"
+)
+
+// findMethod returns the newest version of the method that matches the constraint, or
+// the latest stable version when there is no constraint
+func findMethod(ctx context.Context, db *database.Database, name, constraint string) (method model.Method, err error) {
+	var (
+		versions []model.Method
+		parsed   version.Constraint
+	)
+	if parsed, err = version.ParseConstraint(constraint); err != nil {
+		return method, itserrors.ErrValidation.WithMessage(err.Error())
+	}
+	if err = db.WithContext(ctx).Where("name = ?", name).Find(&versions).Error; err != nil {
+		return
+	}
+	if len(versions) == 0 {
+		return method, itserrors.ErrNotFound.WithMessage(fmt.Sprintf("Method %s not found", name))
+	}
+
+	latest, found := version.Latest(lo.Map(versions, func(method model.Method, _ int) string { return method.Version }), parsed)
+	if !found {
+		if constraint == "" {
+			return method, itserrors.ErrNotFound.WithMessage(fmt.Sprintf("Method %s has no stable version", name))
+		}
+		return method, itserrors.ErrNotFound.WithMessage(fmt.Sprintf("Method %s has no version matching %s", name, constraint))
+	}
+	method, _ = lo.Find(versions, func(method model.Method) bool { return method.Version == latest })
+	return method, nil
+}
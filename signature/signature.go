@@ -0,0 +1,130 @@
+// Package signature signs the requests FED ITS sends to provider webhooks and lets
+// providers verify them.
+//
+// The X-Signature header carries a timestamp, a request ID used as nonce and one or
+// more HMAC-SHA256 signatures:
+//
+//	X-Signature: t=1686830400,id=9f86d081884c7d65,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd
+//
+// Each v1 signature is the hex encoded HMAC-SHA256, keyed by the provider secret, of
+// the timestamp, the request ID, the method and the raw body joined by dots. Requests
+// older than the tolerance or whose ID was already seen must be rejected.
+//
+// Providers sign their management calls to FED ITS, like PATCH /provider/{slug}, the
+// same way, using Request to name the HTTP method and request URI, the path with its
+// query string, as the signed method.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Header is the HTTP header that carries the signature
+	Header = "X-Signature"
+	// Version is the scheme of the signatures produced by this package
+	Version = "v1"
+	// DefaultTolerance is how old a signed request may be when it is verified
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMalformed = errors.New("malformed signature header")
+	ErrExpired   = errors.New("signature timestamp is outside the tolerance")
+	ErrMismatch  = errors.New("no signature matches the request")
+	ErrReplayed  = errors.New("request was already received")
+)
+
+// Signature is a parsed signature header
+type Signature struct {
+	Timestamp  time.Time
+	ID         string
+	Signatures []string
+}
+
+// NewID returns a random request ID to be used as nonce
+func NewID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Sign returns the header value signing the request with every given secret
+func Sign(timestamp time.Time, id, method string, body []byte, secrets ...string) string {
+	parts := []string{fmt.Sprintf("t=%d", timestamp.Unix()), fmt.Sprintf("id=%s", id)}
+	for _, secret := range secrets {
+		parts = append(parts, fmt.Sprintf("%s=%s", Version, Compute(secret, timestamp.Unix(), id, method, body)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Compute returns the hex encoded HMAC-SHA256 of the signed content
+func Compute(secret string, timestamp int64, id, method string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.%s.%s.", timestamp, id, method)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Request names an HTTP request as the method of a signature, like "PATCH /provider/slug"
+// or "POST /provider/slug/methods/Method?version=1.2.0"
+func Request(method, uri string) string {
+	return fmt.Sprintf("%s %s", strings.ToUpper(method), uri)
+}
+
+// Parse reads a signature header. Signatures of unknown versions are ignored.
+func Parse(header string) (signature Signature, err error) {
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return Signature{}, ErrMalformed
+		}
+		switch key {
+		case "t":
+			var unix int64
+			if unix, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return Signature{}, ErrMalformed
+			}
+			signature.Timestamp = time.Unix(unix, 0)
+		case "id":
+			signature.ID = value
+		case Version:
+			signature.Signatures = append(signature.Signatures, value)
+		}
+	}
+	if signature.Timestamp.IsZero() || signature.ID == "" || len(signature.Signatures) == 0 {
+		return Signature{}, ErrMalformed
+	}
+	return signature, nil
+}
+
+// Verify checks that the header signs the request with any of the secrets and that it
+// is not older, or newer, than the tolerance. It does not check for replays, the
+// returned ID must be checked against the IDs already received.
+func Verify(header, method string, body []byte, tolerance time.Duration, secrets ...string) (signature Signature, err error) {
+	if signature, err = Parse(header); err != nil {
+		return
+	}
+	if skew := time.Since(signature.Timestamp); skew > tolerance || skew < -tolerance {
+		return signature, ErrExpired
+	}
+	for _, secret := range secrets {
+		expected := Compute(secret, signature.Timestamp.Unix(), signature.ID, method, body)
+		for _, candidate := range signature.Signatures {
+			if hmac.Equal([]byte(expected), []byte(candidate)) {
+				return signature, nil
+			}
+		}
+	}
+	return signature, ErrMismatch
+}
@@ -0,0 +1,212 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	secret         = "current-secret"
+	previousSecret = "previous-secret"
+	method         = "GetRoadClosures"
+	tolerance      = 5 * time.Minute
+)
+
+var body = []byte(`{"method":"GetRoadClosures","params":["BR-101"]}`)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		header  string
+		method  string
+		body    []byte
+		secrets []string
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			header:  Sign(now, "id-1", method, body, secret),
+			secrets: []string{secret},
+		},
+		{
+			name:    "old but within tolerance",
+			header:  Sign(now.Add(-tolerance+time.Minute), "id-1", method, body, secret),
+			secrets: []string{secret},
+		},
+		{
+			name:    "older than tolerance",
+			header:  Sign(now.Add(-tolerance-time.Minute), "id-1", method, body, secret),
+			secrets: []string{secret},
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "newer than tolerance",
+			header:  Sign(now.Add(tolerance+time.Minute), "id-1", method, body, secret),
+			secrets: []string{secret},
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "unknown secret",
+			header:  Sign(now, "id-1", method, body, "other-secret"),
+			secrets: []string{secret},
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "tampered body",
+			header:  Sign(now, "id-1", method, body, secret),
+			body:    []byte(`{"method":"GetRoadClosures","params":["BR-116"]}`),
+			secrets: []string{secret},
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "tampered method",
+			header:  Sign(now, "id-1", method, body, secret),
+			method:  "GetRoute",
+			secrets: []string{secret},
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "tampered id",
+			header:  strings.Replace(Sign(now, "id-1", method, body, secret), "id=id-1", "id=id-2", 1),
+			secrets: []string{secret},
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "several v1 entries verified with the current secret",
+			header:  Sign(now, "id-1", method, body, previousSecret, secret),
+			secrets: []string{secret},
+		},
+		{
+			name:    "several v1 entries verified with the previous secret",
+			header:  Sign(now, "id-1", method, body, previousSecret, secret),
+			secrets: []string{previousSecret},
+		},
+		{
+			name:    "several secrets verifying a single v1 entry",
+			header:  Sign(now, "id-1", method, body, previousSecret),
+			secrets: []string{secret, previousSecret},
+		},
+		{
+			name:    "unknown versions are ignored",
+			header:  Sign(now, "id-1", method, body, secret) + ",v0=deadbeef",
+			secrets: []string{secret},
+		},
+		{
+			name:    "malformed header",
+			header:  "not a signature",
+			secrets: []string{secret},
+			wantErr: ErrMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.method == "" {
+				tt.method = method
+			}
+			if tt.body == nil {
+				tt.body = body
+			}
+			_, err := Verify(tt.header, tt.method, tt.body, tolerance, tt.secrets...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    Signature
+		wantErr bool
+	}{
+		{
+			name:   "single signature",
+			header: "t=1686830400,id=abc,v1=ff",
+			want:   Signature{Timestamp: time.Unix(1686830400, 0), ID: "abc", Signatures: []string{"ff"}},
+		},
+		{
+			name:   "several signatures and spaces",
+			header: "t=1686830400, id=abc, v1=ff, v1=ee",
+			want:   Signature{Timestamp: time.Unix(1686830400, 0), ID: "abc", Signatures: []string{"ff", "ee"}},
+		},
+		{name: "empty", header: "", wantErr: true},
+		{name: "part without value", header: "t=1686830400,id=abc,v1", wantErr: true},
+		{name: "timestamp not a number", header: "t=yesterday,id=abc,v1=ff", wantErr: true},
+		{name: "missing timestamp", header: "id=abc,v1=ff", wantErr: true},
+		{name: "missing id", header: "t=1686830400,v1=ff", wantErr: true},
+		{name: "missing signature", header: "t=1686830400,id=abc", wantErr: true},
+		{name: "only unknown versions", header: "t=1686830400,id=abc,v2=ff", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMalformed) {
+					t.Errorf("Parse() error = %v, want %v", err, ErrMalformed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.Timestamp.Equal(tt.want.Timestamp) || got.ID != tt.want.ID || fmt.Sprint(got.Signatures) != fmt.Sprint(tt.want.Signatures) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	if got, want := Request("post", "/provider/acme/methods/GetRoute?version=1.2.0"), "POST /provider/acme/methods/GetRoute?version=1.2.0"; got != want {
+		t.Errorf("Request() = %q, want %q", got, want)
+	}
+}
+
+func TestVerifierReplay(t *testing.T) {
+	var (
+		verifier = NewVerifier(secret)
+		header   = Sign(time.Now(), "id-1", method, body, secret)
+	)
+	request := func(header string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+		r.Header.Set(Header, header)
+		return r
+	}
+
+	first := request(header)
+	if err := verifier.Verify(first); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if restored, _ := io.ReadAll(first.Body); string(restored) != string(body) {
+		t.Errorf("Verify() left body %q, want it restored", restored)
+	}
+	if err := verifier.Verify(request(header)); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Verify() of a replay error = %v, want %v", err, ErrReplayed)
+	}
+	if err := verifier.Verify(request(Sign(time.Now(), "id-2", method, body, secret))); err != nil {
+		t.Errorf("Verify() of a new request error = %v", err)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	if !store.Remember("id-1", time.Millisecond) {
+		t.Fatalf("Remember() = false for a new id")
+	}
+	if store.Remember("id-1", time.Millisecond) {
+		t.Errorf("Remember() = true for an id already stored")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !store.Remember("id-1", time.Millisecond) {
+		t.Errorf("Remember() = false for an expired id")
+	}
+}
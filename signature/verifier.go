@@ -0,0 +1,82 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the request IDs already received
+type NonceStore interface {
+	// Remember stores the ID for ttl and reports false if it was already stored
+	Remember(id string, ttl time.Duration) bool
+}
+
+// Verifier validates the webhook requests a provider receives from FED ITS
+type Verifier struct {
+	Secret    string
+	Tolerance time.Duration
+	Nonces    NonceStore
+}
+
+// NewVerifier builds a verifier with the default tolerance and an in memory nonce store
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{Secret: secret, Tolerance: DefaultTolerance, Nonces: NewMemoryNonceStore()}
+}
+
+// Verify checks the signature of a webhook request. The body is read and restored, so
+// the request can still be decoded afterwards.
+func (v *Verifier) Verify(r *http.Request) (err error) {
+	var (
+		body    []byte
+		payload struct {
+			Method string `json:"method"`
+		}
+		signature Signature
+	)
+	if body, err = io.ReadAll(r.Body); err != nil {
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+
+	if signature, err = Verify(r.Header.Get(Header), payload.Method, body, v.Tolerance, v.Secret); err != nil {
+		return
+	}
+	if v.Nonces != nil && !v.Nonces.Remember(signature.ID, 2*v.Tolerance) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// MemoryNonceStore keeps the request IDs in memory, it fits a single instance provider
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}}
+}
+
+func (s *MemoryNonceStore) Remember(id string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for nonce, expires := range s.nonces {
+		if now.After(expires) {
+			delete(s.nonces, nonce)
+		}
+	}
+	if _, seen := s.nonces[id]; seen {
+		return false
+	}
+	s.nonces[id] = now.Add(ttl)
+	return true
+}